@@ -0,0 +1,346 @@
+/*
+ JSON API for Sudoku tools.
+ Puzzles are exchanged as 81 character strings in row order using
+ digits 1-9 for givens and 0 or . for empty cells.  Errors are
+ returned as {"error": "..."} with a 4xx status code.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	patternMinimalClues  = "/api/minimal-clues"  // minimal clue set for a solved grid
+	patternExplain       = "/api/explain"        // logical solving steps
+	patternMaxBlanks     = "/api/max-blanks"     // blanks possible at a difficulty
+	patternFingerprint   = "/api/fingerprint"    // canonical puzzle hash
+	patternGrade         = "/api/grade"          // difficulty label and branching factor
+	patternIsProper      = "/api/is-proper"      // unique and minimal check
+	patternSolveFrames   = "/api/solve-frames"   // board after each logical deduction
+	patternDigitProgress = "/api/digit-progress" // placements made of each digit
+)
+
+// Clue is a given digit at a grid location
+type Clue struct {
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+	Value int `json:"value"`
+}
+
+// writeJSON sends v as the JSON response body with the status code
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Write JSON response error: %v\n", err)
+	}
+}
+
+// writeJSONError sends an error message as the JSON response body
+func writeJSONError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// setPuzzleHeaders describes a generated or served puzzle in the
+// X-Sudoku-Difficulty and X-Sudoku-Clues response headers
+func setPuzzleHeaders(w http.ResponseWriter, p Grid) {
+	w.Header().Set("X-Sudoku-Difficulty", p.Grade())
+	w.Header().Set("X-Sudoku-Clues", strconv.Itoa(p.Clues()))
+}
+
+// decodeJSON reads the POST request body, at most maxBodySize bytes, into v,
+// reporting any failure to the client
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method must be POST")
+		return false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// handleMinimalClues reduces a solved grid to one minimal set of clues with a unique solution
+func handleMinimalClues(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Grid   string `json:"grid"`   // solved or uniquely solvable grid
+		Seed   int64  `json:"seed"`   // seed for the clue removal order
+		Report bool   `json:"report"` // report the first clue that could not be removed
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Grid)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if g.CountSolutions(2) != 1 {
+		writeJSONError(w, http.StatusBadRequest, "grid must have a unique solution")
+		return
+	}
+
+	min, amb := g.MinimizeReport(rand.New(rand.NewSource(req.Seed)))
+	setPuzzleHeaders(w, min)
+	var resp struct {
+		Puzzle    string     `json:"puzzle"`
+		Size      int        `json:"size"`
+		Clues     []Clue     `json:"clues"`
+		Ambiguity *Ambiguity `json:"firstAmbiguity,omitempty"`
+	}
+	resp.Puzzle = min.String()
+	if req.Report {
+		resp.Ambiguity = amb
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if min[row][col] != 0 {
+				resp.Clues = append(resp.Clues, Clue{Row: row, Col: col, Value: min[row][col]})
+			}
+		}
+	}
+	resp.Size = len(resp.Clues)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleExplain solves a puzzle by logic, returning each placement and elimination
+func handleExplain(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	steps, final := g.Explain()
+	if steps == nil {
+		steps = []Step{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Steps  []Step `json:"steps"`
+		Grid   string `json:"grid"`
+		Solved bool   `json:"solved"`
+	}{steps, final.String(), final.Clues() == rows*cols})
+}
+
+// handleMaxBlanks returns the most cells of a solved grid that can be
+// blanked while the puzzle stays uniquely solvable and grades exactly the
+// difficulty query parameter.  An exhaustive search over clue subsets is
+// out of reach, so the maximum is taken over maxGenAttempts removal orders
+// drawn from the seed, each blanking cells until no further blank keeps
+// the puzzle unique and within the difficulty.
+func handleMaxBlanks(w http.ResponseWriter, r *http.Request) {
+	level, ok := parseDifficulty(r.URL.Query().Get("difficulty"))
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "difficulty must be easy, medium, hard, or expert")
+		return
+	}
+	var req struct {
+		Grid string `json:"grid"` // solved grid
+		Seed int64  `json:"seed"` // seed for the clue removal orders
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Grid)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if g.Clues() != rows*cols || !g.GivensConsistent() {
+		writeJSONError(w, http.StatusBadRequest, "grid must be a complete valid solution")
+		return
+	}
+
+	begin := time.Now()
+	rng := rand.New(rand.NewSource(req.Seed))
+	var p Grid
+	found, attempts := false, 0
+	for attempts < maxGenAttempts && r.Context().Err() == nil {
+		attempts++
+		q := g.MinimizeWithin(rng, level)
+		if q.gradeLevel() == level && (!found || q.Clues() < p.Clues()) {
+			p, found = q, true
+		}
+	}
+	recordGeneration(difficultyNames[level], attempts, time.Since(begin))
+	if !found {
+		writeJSONError(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("no %s puzzle found in %d attempts", difficultyNames[level], attempts))
+		return
+	}
+	grade := p.Grade()
+	if *checkGrade {
+		checkGenerated(p.String(), grade)
+	}
+	setPuzzleHeaders(w, p)
+	writeJSON(w, http.StatusOK, struct {
+		Difficulty string `json:"difficulty"`
+		Blanks     int    `json:"blanks"`
+		Puzzle     string `json:"puzzle"`
+		Grade      string `json:"grade"`
+	}{difficultyNames[level], rows*cols - p.Clues(), p.String(), grade})
+}
+
+// handleFingerprint returns the GivensHash of a puzzle, which is the same
+// for every rotation, reflection, and relabeling of it
+func handleFingerprint(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Fingerprint string `json:"fingerprint"`
+		Canonical   string `json:"canonical"`
+	}{g.GivensHash(), g.Canonical().String()})
+}
+
+// handleGrade rates a puzzle by the techniques it needs and by its
+// branching factor.  The optional techniques list restricts the logical
+// solver to those techniques.
+func handleGrade(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle     string   `json:"puzzle"`
+		Techniques []string `json:"techniques"` // all implemented when absent
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !g.GivensConsistent() {
+		writeJSONError(w, http.StatusBadRequest, "puzzle breaks the rules")
+		return
+	}
+	grade := g.Grade()
+	if req.Techniques != nil {
+		if grade, err = g.GradeUsing(req.Techniques); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Grade           string  `json:"grade"`
+		BranchingFactor float64 `json:"branchingFactor"`
+		Clues           int     `json:"clues"`
+	}{grade, g.BranchingFactor(), g.Clues()})
+}
+
+// handleIsProper reports whether a puzzle is proper, that is uniquely
+// solvable and minimal, listing any clue that could be removed
+func handleIsProper(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var resp struct {
+		Proper    bool   `json:"proper"`
+		Unique    bool   `json:"unique"`
+		Minimal   bool   `json:"minimal"`
+		Redundant []Clue `json:"redundant"` // clues removable without losing uniqueness
+	}
+	resp.Unique = g.CountSolutions(2) == 1
+	resp.Redundant = []Clue{}
+	if resp.Unique {
+		resp.Redundant = g.RedundantClues()
+		resp.Minimal = len(resp.Redundant) == 0
+	}
+	resp.Proper = resp.Unique && resp.Minimal
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSolveFrames returns the puzzle query parameter followed by the board
+// after each deduction of the logical solver, for clients animating a solve.
+// Steps that only eliminate candidates repeat the previous board.
+func handleSolveFrames(w http.ResponseWriter, r *http.Request) {
+	g, err := ParseGrid(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !g.GivensConsistent() {
+		writeJSONError(w, http.StatusBadRequest, "puzzle breaks the rules")
+		return
+	}
+	steps, final := g.Explain()
+	frames := []string{g.String()}
+	for _, step := range steps {
+		if p := step.Placement; p != nil {
+			g[p.Row][p.Col] = p.Value
+		}
+		frames = append(frames, g.String())
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Frames []string `json:"frames"`
+		Solved bool     `json:"solved"`
+	}{frames, final.Clues() == rows*cols})
+}
+
+// DigitCounts returns the number of cells holding each digit, indexed by
+// the digit, so index 0 counts the empty cells.  Values outside 0-9 are
+// not counted.
+func (g Grid) DigitCounts() [10]int {
+	var counts [10]int
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if d := g[row][col]; d >= 0 && d <= 9 {
+				counts[d]++
+			}
+		}
+	}
+	return counts
+}
+
+// handleDigitProgress returns for each digit 1-9 how many of its nine
+// placements are on the board in the puzzle query parameter
+func handleDigitProgress(w http.ResponseWriter, r *http.Request) {
+	g, err := ParseGrid(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	type progress struct {
+		Digit  int `json:"digit"`
+		Placed int `json:"placed"`
+	}
+	counts := g.DigitCounts()
+	resp := make([]progress, 0, 9)
+	for d := 1; d <= 9; d++ {
+		resp = append(resp, progress{d, counts[d]})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
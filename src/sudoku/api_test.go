@@ -0,0 +1,485 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// callAPI sends the request to the handler and decodes the JSON response into v
+func callAPI(t *testing.T, h http.HandlerFunc, method, target, body string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v", method, target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandleMinimalClues(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"solution", `{"grid":"` + testSolution + `","seed":3}`, http.StatusOK},
+		{"puzzle", `{"grid":"` + testPuzzle + `"}`, http.StatusOK},
+		{"several solutions", `{"grid":"` + Grid{}.String() + `"}`, http.StatusBadRequest},
+		{"bad grid", `{"grid":"12"}`, http.StatusBadRequest},
+		{"not json", `grid`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Puzzle string `json:"puzzle"`
+			Size   int    `json:"size"`
+			Clues  []Clue `json:"clues"`
+		}
+		code := callAPI(t, handleMinimalClues, http.MethodPost, patternMinimalClues, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		p := mustGrid(t, resp.Puzzle)
+		if p.CountSolutions(2) != 1 || resp.Size != p.Clues() || len(resp.Clues) != resp.Size {
+			t.Errorf("%s: puzzle %s with size %d and %d clues", tt.name, resp.Puzzle, resp.Size, len(resp.Clues))
+		}
+		for _, c := range resp.Clues {
+			if p[c.Row][c.Col] != c.Value {
+				t.Errorf("%s: clue %+v is not in the puzzle", tt.name, c)
+			}
+		}
+	}
+}
+
+func TestDigitCounts(t *testing.T) {
+	var tampered Grid
+	tampered[0][0], tampered[0][1], tampered[0][2] = 10, -1, 5
+	tests := []struct {
+		name string
+		g    Grid
+		want [10]int
+	}{
+		{"empty", Grid{}, [10]int{81}},
+		{"solution", mustGrid(t, testSolution), [10]int{0, 9, 9, 9, 9, 9, 9, 9, 9, 9}},
+		{"puzzle", mustGrid(t, testPuzzle), [10]int{51, 3, 2, 3, 2, 3, 5, 3, 5, 4}},
+		{"out of range", tampered, [10]int{78, 0, 0, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.g.DigitCounts(); got != tt.want {
+			t.Errorf("%s: DigitCounts() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleExplain(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		steps  int // -1 for any number
+		solved bool
+	}{
+		{"singles", `{"puzzle":"` + testPuzzle + `"}`, http.StatusOK, 51, true},
+		{"solved", `{"puzzle":"` + testSolution + `"}`, http.StatusOK, 0, true},
+		{"stalls", `{"puzzle":"` + testStalls + `"}`, http.StatusOK, -1, false},
+		{"bad puzzle", `{"puzzle":"1"}`, http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Steps  []Step `json:"steps"`
+			Grid   string `json:"grid"`
+			Solved bool   `json:"solved"`
+		}
+		code := callAPI(t, handleExplain, http.MethodPost, patternExplain, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code == http.StatusOK && (tt.steps >= 0 && len(resp.Steps) != tt.steps || resp.Solved != tt.solved) {
+			t.Errorf("%s: %d steps solved %v, want %d %v", tt.name, len(resp.Steps), resp.Solved, tt.steps, tt.solved)
+		}
+	}
+}
+
+func TestHandleFingerprint(t *testing.T) {
+	p := mustGrid(t, testPuzzle)
+	relabeled := swapDigits(p.transform(3), [9]int{2, 3, 4, 5, 6, 7, 8, 9, 1})
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		same   bool // fingerprint of testPuzzle
+	}{
+		{"puzzle", http.MethodPost, `{"puzzle":"` + testPuzzle + `"}`, http.StatusOK, true},
+		{"rotated and relabeled", http.MethodPost, `{"puzzle":"` + relabeled.String() + `"}`, http.StatusOK, true},
+		{"other puzzle", http.MethodPost, `{"puzzle":"` + testStalls + `"}`, http.StatusOK, false},
+		{"short", http.MethodPost, `{"puzzle":"53"}`, http.StatusBadRequest, false},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Fingerprint string `json:"fingerprint"`
+			Canonical   string `json:"canonical"`
+		}
+		code := callAPI(t, handleFingerprint, tt.method, patternFingerprint, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if same := resp.Fingerprint == p.GivensHash(); same != tt.same {
+			t.Errorf("%s: fingerprint %s, same as the puzzle %v, want %v", tt.name, resp.Fingerprint, same, tt.same)
+		}
+		if same := resp.Canonical == p.Canonical().String(); same != tt.same {
+			t.Errorf("%s: canonical %s, same as the puzzle %v, want %v", tt.name, resp.Canonical, same, tt.same)
+		}
+	}
+}
+
+func TestMaxBlanks(t *testing.T) {
+	tests := []struct {
+		difficulty string
+		grid       string
+		code       int
+	}{
+		{"easy", testSolution, http.StatusOK},
+		{"medium", testSolution, http.StatusOK},
+		{"hard", testSolution, http.StatusOK},
+		{"expert", testSolution, http.StatusOK},
+		{"trivial", testSolution, http.StatusBadRequest},
+		{"easy", testPuzzle, http.StatusBadRequest},
+		{"easy", "12", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Difficulty string `json:"difficulty"`
+			Blanks     int    `json:"blanks"`
+			Puzzle     string `json:"puzzle"`
+			Grade      string `json:"grade"`
+		}
+		body := `{"grid":"` + tt.grid + `","seed":7}`
+		code := callAPI(t, handleMaxBlanks, http.MethodPost, patternMaxBlanks+"?difficulty="+tt.difficulty, body, &resp)
+		if code != tt.code {
+			t.Errorf("%s %.10s: status code %d, want %d", tt.difficulty, tt.grid, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		p := mustGrid(t, resp.Puzzle)
+		level, _ := parseDifficulty(tt.difficulty)
+		if got := p.gradeLevel(); got != level || resp.Grade != tt.difficulty {
+			t.Errorf("%s: puzzle grades %s, reported %s", tt.difficulty, difficultyNames[got], resp.Grade)
+		}
+		if n := p.CountSolutions(2); n != 1 || resp.Blanks != rows*cols-p.Clues() || !mustGrid(t, tt.grid).RespectsGivens(p) {
+			t.Errorf("%s: %d solutions, %d blanks reported for %d", tt.difficulty, n, resp.Blanks, rows*cols-p.Clues())
+		}
+		// no further blank keeps the puzzle unique and within the difficulty
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				q := p
+				if q[row][col] == 0 {
+					continue
+				}
+				q[row][col] = 0
+				if q.CountSolutions(2) == 1 && q.gradeLevel() <= level {
+					t.Errorf("%s: %s can also be blanked", tt.difficulty, cellName(row, col))
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	big := `{"puzzle":"` + strings.Repeat("0", maxBodySize) + `"}`
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"puzzle", http.MethodPost, `{"puzzle":"` + testPuzzle + `"}`, http.StatusOK},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"not json", http.MethodPost, "puzzle", http.StatusBadRequest},
+		{"too large", http.MethodPost, big, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var req struct {
+			Puzzle string `json:"puzzle"`
+		}
+		rec := httptest.NewRecorder()
+		ok := decodeJSON(rec, httptest.NewRequest(tt.method, patternFingerprint, strings.NewReader(tt.body)), &req)
+		if ok != (tt.code == http.StatusOK) || !ok && rec.Code != tt.code {
+			t.Errorf("%s: decodeJSON = %v with status code %d, want %d", tt.name, ok, rec.Code, tt.code)
+		}
+		if tt.name == "too large" && !strings.Contains(rec.Body.String(), "too large") {
+			t.Errorf("%s: body %q does not report the size", tt.name, rec.Body.String())
+		}
+		if ok && req.Puzzle != testPuzzle {
+			t.Errorf("%s: puzzle %q, want %q", tt.name, req.Puzzle, testPuzzle)
+		}
+	}
+}
+
+func TestHandleGrade(t *testing.T) {
+	tests := []struct {
+		name   string
+		puzzle string
+		code   int
+	}{
+		{"puzzle", testPuzzle, http.StatusOK},
+		{"empty", Grid{}.String(), http.StatusOK},
+		{"breaks the rules", "55" + testPuzzle[2:], http.StatusBadRequest},
+		{"short", "53", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Grade           string  `json:"grade"`
+			BranchingFactor float64 `json:"branchingFactor"`
+			Clues           int     `json:"clues"`
+		}
+		code := callAPI(t, handleGrade, http.MethodPost, patternGrade, `{"puzzle":"`+tt.puzzle+`"}`, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		g := mustGrid(t, tt.puzzle)
+		if resp.Grade != g.Grade() || resp.BranchingFactor != g.BranchingFactor() || resp.Clues != g.Clues() {
+			t.Errorf("%s: response %+v, want %s, %v, %d", tt.name, resp, g.Grade(), g.BranchingFactor(), g.Clues())
+		}
+	}
+}
+
+func TestHandleGradeTechniques(t *testing.T) {
+	tests := []struct {
+		name       string
+		techniques string // JSON list, absent when empty
+		code       int
+		grade      string
+	}{
+		{"all", "", http.StatusOK, "easy"},
+		{"hidden single", `["Hidden single"]`, http.StatusOK, "medium"},
+		{"none", `[]`, http.StatusOK, "expert"},
+		{"unknown", `["Guess"]`, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		body := `{"puzzle":"` + testPuzzle + `"}`
+		if tt.techniques != "" {
+			body = `{"puzzle":"` + testPuzzle + `","techniques":` + tt.techniques + `}`
+		}
+		var resp struct {
+			Grade string `json:"grade"`
+		}
+		code := callAPI(t, handleGrade, http.MethodPost, patternGrade, body, &resp)
+		if code != tt.code || resp.Grade != tt.grade {
+			t.Errorf("%s: status code %d with grade %q, want %d, %q", tt.name, code, resp.Grade, tt.code, tt.grade)
+		}
+	}
+}
+
+func TestHandleIsProper(t *testing.T) {
+	sol := mustGrid(t, testSolution)
+	min := sol.Minimize(rand.New(rand.NewSource(1)))
+	extra := min
+	var added Clue
+	for i := 0; i < rows*cols; i++ {
+		if row, col := i/cols, i%cols; extra[row][col] == 0 {
+			extra[row][col] = sol[row][col]
+			added = Clue{Row: row, Col: col, Value: sol[row][col]}
+			break
+		}
+	}
+	tests := []struct {
+		name      string
+		puzzle    string
+		unique    bool
+		minimal   bool
+		redundant int // -1 for any number including added
+	}{
+		{"minimal", min.String(), true, true, 0},
+		{"one clue extra", extra.String(), true, false, -1},
+		{"solution", testSolution, true, false, rows * cols},
+		{"several solutions", Grid{}.String(), false, false, 0},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Proper    bool   `json:"proper"`
+			Unique    bool   `json:"unique"`
+			Minimal   bool   `json:"minimal"`
+			Redundant []Clue `json:"redundant"`
+		}
+		code := callAPI(t, handleIsProper, http.MethodPost, patternIsProper, `{"puzzle":"`+tt.puzzle+`"}`, &resp)
+		if code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, code)
+			continue
+		}
+		if resp.Unique != tt.unique || resp.Minimal != tt.minimal || resp.Proper != (tt.unique && tt.minimal) {
+			t.Errorf("%s: response %+v, want unique %v and minimal %v", tt.name, resp, tt.unique, tt.minimal)
+		}
+		if tt.redundant >= 0 && len(resp.Redundant) != tt.redundant {
+			t.Errorf("%s: %d redundant clues, want %d", tt.name, len(resp.Redundant), tt.redundant)
+		}
+		if tt.redundant < 0 {
+			found := false
+			for _, c := range resp.Redundant {
+				found = found || c == added
+			}
+			if !found {
+				t.Errorf("%s: redundant clues %v do not include %+v", tt.name, resp.Redundant, added)
+			}
+		}
+	}
+	if code := callAPI(t, handleIsProper, http.MethodPost, patternIsProper, `{"puzzle":"53"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("short puzzle: status code %d, want 400", code)
+	}
+}
+
+func TestPuzzleHeaders(t *testing.T) {
+	pageForm := url.Values{"action": {"new"}, "blankvalues": {"40"}, "seed": {"1"}}.Encode()
+	tests := []struct {
+		name   string
+		h      http.HandlerFunc
+		method string
+		target string
+		body   string
+	}{
+		{"minimal clues", handleMinimalClues, http.MethodPost, patternMinimalClues, `{"grid":"` + testSolution + `"}`},
+		{"max blanks", handleMaxBlanks, http.MethodPost, patternMaxBlanks + "?difficulty=easy", `{"grid":"` + testSolution + `"}`},
+		{"new", handleNew, http.MethodPost, patternNew, `{"seed":1}`},
+		{"initial page", handleSudoku, http.MethodGet, "/", ""},
+		{"new page", handleSudokuSubmit, http.MethodPost, patternSubmit, pageForm},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.target == patternSubmit {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		tt.h(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, rec.Code)
+			continue
+		}
+		// the puzzle is the puzzle field of a JSON response or the givens of a page
+		var p Grid
+		var resp struct {
+			Puzzle string `json:"puzzle"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err == nil {
+			p = mustGrid(t, resp.Puzzle)
+		} else {
+			for _, m := range givensRe.FindAllStringSubmatch(rec.Body.String(), -1) {
+				var row, col, sg int
+				fmt.Sscanf(m[1], "%d_%d_%d", &row, &col, &sg)
+				p[row][col] = int(m[2][0] - '0')
+			}
+		}
+		if p.Clues() == 0 {
+			t.Errorf("%s: no puzzle in the response", tt.name)
+			continue
+		}
+		difficulty, clues := rec.Header().Get("X-Sudoku-Difficulty"), rec.Header().Get("X-Sudoku-Clues")
+		if difficulty != p.Grade() || clues != strconv.Itoa(p.Clues()) {
+			t.Errorf("%s: headers %q, %q, want %q, %d", tt.name, difficulty, clues, p.Grade(), p.Clues())
+		}
+	}
+}
+
+func TestHandleSolveFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		puzzle string
+		code   int
+		solved bool
+	}{
+		{"puzzle", testPuzzle, http.StatusOK, true},
+		{"stalls", testStalls, http.StatusOK, false},
+		{"solution", testSolution, http.StatusOK, true},
+		{"breaks the rules", "55" + testPuzzle[2:], http.StatusBadRequest, false},
+		{"short", "53", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Frames []string `json:"frames"`
+			Solved bool     `json:"solved"`
+		}
+		code := callAPI(t, handleSolveFrames, http.MethodGet, patternSolveFrames+"?puzzle="+tt.puzzle, "", &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		steps, final := mustGrid(t, tt.puzzle).Explain()
+		if resp.Solved != tt.solved || len(resp.Frames) != len(steps)+1 || resp.Frames[0] != tt.puzzle || resp.Frames[len(resp.Frames)-1] != final.String() {
+			t.Errorf("%s: %d frames from %s to %s, solved %v", tt.name, len(resp.Frames), resp.Frames[0], resp.Frames[len(resp.Frames)-1], resp.Solved)
+			continue
+		}
+		// each frame places at most one digit on the one before
+		for i := 1; i < len(resp.Frames); i++ {
+			prev, cur := mustGrid(t, resp.Frames[i-1]), mustGrid(t, resp.Frames[i])
+			if n := cur.Clues() - prev.Clues(); n < 0 || n > 1 || !cur.RespectsGivens(prev) {
+				t.Errorf("%s: frame %d adds %d digits to the one before", tt.name, i, n)
+			}
+		}
+	}
+}
+
+func TestHandleDigitProgress(t *testing.T) {
+	partial := []byte(testPuzzle)
+	partial[2] = '4' // R1C3
+	tests := []struct {
+		name   string
+		puzzle string
+		code   int
+		placed []int // for digits 1-9
+	}{
+		{"puzzle", testPuzzle, http.StatusOK, []int{3, 2, 3, 2, 3, 5, 3, 5, 4}},
+		{"one entry", string(partial), http.StatusOK, []int{3, 2, 3, 3, 3, 5, 3, 5, 4}},
+		{"solution", testSolution, http.StatusOK, []int{9, 9, 9, 9, 9, 9, 9, 9, 9}},
+		{"empty", strings.Repeat("0", rows*cols), http.StatusOK, []int{0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"short", "53", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		var resp []struct {
+			Digit  int `json:"digit"`
+			Placed int `json:"placed"`
+		}
+		code := callAPI(t, handleDigitProgress, http.MethodGet, patternDigitProgress+"?puzzle="+tt.puzzle, "", &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if len(resp) != len(tt.placed) {
+			t.Errorf("%s: %d digits, want %d", tt.name, len(resp), len(tt.placed))
+			continue
+		}
+		for i, p := range resp {
+			if p.Digit != i+1 || p.Placed != tt.placed[i] {
+				t.Errorf("%s: digit %d placed %d times, want digit %d placed %d times", tt.name, p.Digit, p.Placed, i+1, tt.placed[i])
+			}
+		}
+	}
+}
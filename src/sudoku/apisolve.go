@@ -0,0 +1,162 @@
+/*
+ Strictly validated solve API.
+ POST /api/solve takes {"puzzle": "<81 characters>", "logicOnly": false}
+ and nothing else; logicOnly is optional and limits the solver to the
+ techniques of the logical solver.  A body that breaks the schema is
+ answered with 400 and every problem found, each naming the field and
+ one of these codes:
+
+   invalid_json   the body is not a JSON object
+   unknown_field  the object has a member other than the documented ones
+   missing_field  a required member is absent
+   wrong_type     a member has the wrong JSON type
+   wrong_length   the puzzle is not 81 characters long
+   bad_char       the puzzle has a character other than 1-9, 0, or .;
+                  position is its index from 0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const (
+	patternSolve = "/api/solve" // solve a puzzle
+	maxBodySize  = 1 << 16      // largest JSON body accepted by the API
+)
+
+// FieldError is one way a request body breaks the API schema
+type FieldError struct {
+	Field    string `json:"field"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Position *int   `json:"position,omitempty"`
+}
+
+// solveRequest is the validated body of a solve request
+type solveRequest struct {
+	Puzzle    Grid
+	LogicOnly bool // solve without guessing
+}
+
+// validateSolveRequest checks the body against the solve API schema
+func validateSolveRequest(body []byte) (solveRequest, []FieldError) {
+	var req solveRequest
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return req, []FieldError{{Field: "", Code: "invalid_json", Message: "body must be a JSON object"}}
+	}
+
+	// report unknown members in a stable order
+	var names []string
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []FieldError
+	for _, name := range names {
+		if name != "puzzle" && name != "logicOnly" {
+			errs = append(errs, FieldError{Field: name, Code: "unknown_field",
+				Message: fmt.Sprintf("unknown field %q", name)})
+		}
+	}
+
+	if raw, ok := members["logicOnly"]; ok {
+		if err := json.Unmarshal(raw, &req.LogicOnly); err != nil || string(raw) == "null" {
+			errs = append(errs, FieldError{Field: "logicOnly", Code: "wrong_type", Message: "logicOnly must be true or false"})
+		}
+	}
+
+	raw, ok := members["puzzle"]
+	if !ok {
+		return req, append(errs, FieldError{Field: "puzzle", Code: "missing_field", Message: "puzzle is required"})
+	}
+	var puzzle string
+	if err := json.Unmarshal(raw, &puzzle); err != nil || string(raw) == "null" {
+		return req, append(errs, FieldError{Field: "puzzle", Code: "wrong_type", Message: "puzzle must be a string"})
+	}
+	if len(puzzle) != rows*cols {
+		return req, append(errs, FieldError{Field: "puzzle", Code: "wrong_length",
+			Message: fmt.Sprintf("puzzle must be %d characters, not %d", rows*cols, len(puzzle))})
+	}
+	for i := 0; i < len(puzzle); i++ {
+		ch := puzzle[i]
+		if (ch < '0' || ch > '9') && ch != '.' {
+			pos := i
+			errs = append(errs, FieldError{Field: "puzzle", Code: "bad_char", Position: &pos,
+				Message: fmt.Sprintf("puzzle character %q at position %d must be 1-9, 0, or .", ch, i)})
+		}
+	}
+	if len(errs) > 0 {
+		return req, errs
+	}
+	req.Puzzle, _ = ParseGrid(puzzle)
+	return req, nil
+}
+
+// handleSolve validates the request strictly and returns the solution of the puzzle
+func handleSolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method must be POST")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req, errs := validateSolveRequest(body)
+	if len(errs) > 0 {
+		writeJSON(w, http.StatusBadRequest, struct {
+			Error  string       `json:"error"`
+			Errors []FieldError `json:"errors"`
+		}{errs[0].Message, errs})
+		return
+	}
+
+	if req.LogicOnly {
+		solveLogicOnly(w, req.Puzzle)
+		return
+	}
+
+	sols := req.Puzzle.CachedSolutions()
+	if len(sols) == 0 {
+		writeJSONError(w, http.StatusUnprocessableEntity, "puzzle has no solution")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Solution   string `json:"solution"`
+		Unique     bool   `json:"unique"`
+		Confidence string `json:"confidence"` // logic only or required search
+	}{sols[0].String(), len(sols) == 1, req.Puzzle.Confidence()})
+}
+
+// solveLogicOnly applies only the logical techniques, returning the partly
+// solved board when they stall instead of falling back to guessing
+func solveLogicOnly(w http.ResponseWriter, p Grid) {
+	if !p.GivensConsistent() {
+		writeJSONError(w, http.StatusUnprocessableEntity, "puzzle has no solution")
+		return
+	}
+	_, final := p.Explain()
+	if final.Clues() < rows*cols {
+		writeJSON(w, http.StatusOK, struct {
+			Grid   string `json:"grid"`
+			Solved bool   `json:"solved"`
+			Status string `json:"status"`
+		}{final.String(), false, "requires guessing or an unsupported technique"})
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Solution string `json:"solution"`
+		Solved   bool   `json:"solved"`
+		Status   string `json:"status"`
+		Grade    string `json:"grade"`
+	}{final.String(), true, "solved by logic", p.Grade()})
+}
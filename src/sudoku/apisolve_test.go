@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateSolveRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		codes []string
+	}{
+		{"puzzle", `{"puzzle":"` + testPuzzle + `"}`, nil},
+		{"logic only", `{"puzzle":"` + testPuzzle + `","logicOnly":true}`, nil},
+		{"array", `[]`, []string{"invalid_json"}},
+		{"unknown", `{"puzzle":"` + testPuzzle + `","hint":1}`, []string{"unknown_field"}},
+		{"missing", `{}`, []string{"missing_field"}},
+		{"puzzle number", `{"puzzle":5}`, []string{"wrong_type"}},
+		{"logicOnly string", `{"puzzle":"` + testPuzzle + `","logicOnly":"yes"}`, []string{"wrong_type"}},
+		{"short", `{"puzzle":"123"}`, []string{"wrong_length"}},
+		{"bad chars", `{"puzzle":"x` + testPuzzle[1:80] + `y"}`, []string{"bad_char", "bad_char"}},
+	}
+	for _, tt := range tests {
+		_, errs := validateSolveRequest([]byte(tt.body))
+		var codes []string
+		for _, e := range errs {
+			codes = append(codes, e.Code)
+		}
+		if strings.Join(codes, ",") != strings.Join(tt.codes, ",") {
+			t.Errorf("%s: codes %v, want %v", tt.name, codes, tt.codes)
+		}
+	}
+}
+
+func TestHandleSolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		solved bool
+		status string
+	}{
+		{"search", `{"puzzle":"` + testPuzzle + `"}`, http.StatusOK, false, ""},
+		{"singles", `{"puzzle":"` + testPuzzle + `","logicOnly":true}`, http.StatusOK, true, "solved by logic"},
+		{"stalls", `{"puzzle":"` + testStalls + `","logicOnly":true}`, http.StatusOK, false, "requires guessing or an unsupported technique"},
+		{"no solution", `{"puzzle":"55` + testPuzzle[2:] + `","logicOnly":true}`, http.StatusUnprocessableEntity, false, ""},
+		{"bad schema", `{"puzzle":1}`, http.StatusBadRequest, false, ""},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Solution string `json:"solution"`
+			Solved   bool   `json:"solved"`
+			Status   string `json:"status"`
+		}
+		code := callAPI(t, handleSolve, http.MethodPost, patternSolve, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if tt.name == "search" && resp.Solution != testSolution {
+			t.Errorf("%s: solution %s, want %s", tt.name, resp.Solution, testSolution)
+		}
+		if resp.Solved != tt.solved || resp.Status != tt.status {
+			t.Errorf("%s: solved %v status %q, want %v %q", tt.name, resp.Solved, resp.Status, tt.solved, tt.status)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ Batch grading of puzzle collections.
+ The request body holds one 81 character puzzle per line and the response
+ streams one JSON object per line as each puzzle is graded, so large
+ collections are processed with bounded memory.  Blank lines and lines
+ starting with # are skipped but still counted in the line numbers.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const patternGradeBatch = "/api/grade-batch" // grade newline delimited puzzles
+
+// batchResult is the grading of one line of a batch
+type batchResult struct {
+	Line       int      `json:"line"`
+	Puzzle     string   `json:"puzzle,omitempty"`
+	Grade      string   `json:"grade,omitempty"`
+	Techniques []string `json:"techniques,omitempty"` // in order of first use
+	Unique     bool     `json:"unique"`
+	Solutions  int      `json:"solutions"` // 0, 1, or 2 for more than one
+	Error      string   `json:"error,omitempty"`
+}
+
+// Techniques returns the names of the techniques the logical solver uses
+// on the grid, in the order they are first applied
+func (g Grid) Techniques() []string {
+	var names []string
+	seen := make(map[string]bool)
+	steps, _ := g.Explain()
+	for _, step := range steps {
+		if !seen[step.Technique] {
+			seen[step.Technique] = true
+			names = append(names, step.Technique)
+		}
+	}
+	return names
+}
+
+// handleGradeBatch grades each puzzle of the posted lines, writing the
+// results as newline delimited JSON in input order
+func handleGradeBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method must be POST")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	input := bufio.NewScanner(r.Body)
+	for line := 1; input.Scan(); line++ {
+		if r.Context().Err() != nil {
+			return
+		}
+		text := strings.TrimSpace(input.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		res := batchResult{Line: line}
+		if g, err := ParseGrid(text); err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Puzzle = g.String()
+			res.Solutions = g.CountSolutions(2)
+			res.Unique = res.Solutions == 1
+			if res.Solutions > 0 {
+				res.Grade = g.Grade()
+				res.Techniques = g.Techniques()
+			}
+		}
+		if err := enc.Encode(res); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := input.Err(); err != nil {
+		enc.Encode(batchResult{Error: err.Error()})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTechniques(t *testing.T) {
+	hole := mustGrid(t, testSolution)
+	hole[4][4] = 0
+	tests := []struct {
+		name string
+		g    Grid
+		want []string
+	}{
+		{"solution", mustGrid(t, testSolution), nil},
+		{"one hole", hole, []string{"Naked single"}},
+		{"puzzle", mustGrid(t, testPuzzle), []string{"Naked single"}},
+		{"several solutions", Grid{}, nil},
+	}
+	for _, tt := range tests {
+		if got := tt.g.Techniques(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Techniques = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleGradeBatch(t *testing.T) {
+	body := strings.Join([]string{
+		"# collection",
+		testPuzzle,
+		"",
+		"53",
+		Grid{}.String(),
+		"55" + testPuzzle[2:],
+	}, "\n")
+	want := []batchResult{
+		{Line: 2, Puzzle: testPuzzle, Grade: mustGrid(t, testPuzzle).Grade(), Techniques: mustGrid(t, testPuzzle).Techniques(), Unique: true, Solutions: 1},
+		{Line: 4, Error: errGridFormat.Error()},
+		{Line: 5, Puzzle: Grid{}.String(), Grade: Grid{}.Grade(), Techniques: Grid{}.Techniques(), Solutions: 2},
+		{Line: 6, Puzzle: "55" + testPuzzle[2:]},
+	}
+	rec := httptest.NewRecorder()
+	handleGradeBatch(rec, httptest.NewRequest(http.MethodPost, patternGradeBatch, strings.NewReader(body)))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("status code %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got []batchResult
+	for lines := bufio.NewScanner(rec.Body); lines.Scan(); {
+		var res batchResult
+		if err := json.Unmarshal(lines.Bytes(), &res); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		got = append(got, res)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d results, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if code := callAPI(t, handleGradeBatch, http.MethodGet, patternGradeBatch, "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("get: status code %d, want 405", code)
+	}
+}
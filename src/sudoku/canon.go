@@ -0,0 +1,273 @@
+/*
+ Canonical form of a Sudoku grid.
+ Relabeling the digits, transposing, or reordering the bands, the stacks,
+ or the rows and columns within them produces an equivalent puzzle with
+ the same solving path; rotations and reflections are combinations of
+ these.  The canonical form is the smallest 81 character string over all
+ those transforms, each relabeled so digits are numbered in order of first
+ appearance.  A search over the row orders prunes those that already sort
+ after the best grid found.
+ Scrambling goes the other way, applying random transforms of the same
+ kinds, so one puzzle can be shown in many different looking forms that
+ all share its canonical form.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const patternScramble = "/api/scramble" // random equivalent puzzle
+
+// transform returns one of the eight rotations and reflections of the grid
+func (g Grid) transform(k int) Grid {
+	var out Grid
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			r, c := row, col
+			if k&1 != 0 { // transpose
+				r, c = c, r
+			}
+			if k&2 != 0 { // reflect rows
+				r = rows - 1 - r
+			}
+			if k&4 != 0 { // reflect columns
+				c = cols - 1 - c
+			}
+			out[r][c] = g[row][col]
+		}
+	}
+	return out
+}
+
+// relabel renumbers the digits in order of first appearance in row order
+func (g Grid) relabel() Grid {
+	var label [10]int
+	next := 1
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			d := g[row][col]
+			if d == 0 {
+				continue
+			}
+			if label[d] == 0 {
+				label[d] = next
+				next++
+			}
+			g[row][col] = label[d]
+		}
+	}
+	return g
+}
+
+// perms3 are the six orders of three things
+var perms3 = [6][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+// colPerms are the 1296 orders of the nine columns that keep each stack of
+// three together: the orders of the stacks times those within each stack
+var colPerms = func() [][9]int {
+	var perms [][9]int
+	for _, stacks := range perms3 {
+		for _, p0 := range perms3 {
+			for _, p1 := range perms3 {
+				for _, p2 := range perms3 {
+					within := [3][3]int{p0, p1, p2}
+					var perm [9]int
+					for i, stack := range stacks {
+						for j, line := range within[i] {
+							perm[i*3+j] = stack*3 + line
+						}
+					}
+					perms = append(perms, perm)
+				}
+			}
+		}
+	}
+	return perms
+}()
+
+// canonSearch finds the smallest relabeled grid over the row orders that
+// keep each band together
+type canonSearch struct {
+	in    Grid      // grid with its columns already ordered
+	out   Grid      // rows placed so far
+	src   [rows]int // row of in placed at each row of out
+	used  [rows]bool
+	best  Grid
+	found bool
+}
+
+// compare orders the first n rows of out against those of best
+func (cs *canonSearch) compare(n int) int {
+	for row := 0; row < n; row++ {
+		for col := 0; col < cols; col++ {
+			if a, b := cs.out[row][col], cs.best[row][col]; a != b {
+				if a < b {
+					return -1
+				}
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// search places a row of in at out row i, relabeling its digits with label
+// and the next unused label, and goes on to the next row while out can
+// still beat the best grid found
+func (cs *canonSearch) search(i int, label [10]int, next int) {
+	if i == rows {
+		cs.best, cs.found = cs.out, true
+		return
+	}
+	// a band starts at any row of an unused band, later rows stay in it;
+	// bands are placed whole, so the rows of used bands are all used
+	first, last := 0, rows
+	if i%3 != 0 {
+		band := cs.src[i-1] / 3
+		first, last = band*3, band*3+3
+	}
+	for r := first; r < last; r++ {
+		if cs.used[r] || cs.twin(r) {
+			continue
+		}
+		lab, nxt := label, next
+		for col := 0; col < cols; col++ {
+			d := cs.in[r][col]
+			if d != 0 && lab[d] == 0 {
+				lab[d] = nxt
+				nxt++
+			}
+			cs.out[i][col] = lab[d]
+		}
+		if cs.found && cs.compare(i+1) > 0 {
+			continue
+		}
+		cs.used[r] = true
+		cs.src[i] = r
+		cs.search(i+1, lab, nxt)
+		cs.used[r] = false
+	}
+}
+
+// twin tells whether an unused row before r in its band holds the same
+// digits, so placing r instead gives the same grids
+func (cs *canonSearch) twin(r int) bool {
+	for r2 := r - r%3; r2 < r; r2++ {
+		if !cs.used[r2] && cs.in[r2] == cs.in[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// Canonical returns the representative of the grid's equivalence class
+// under digit relabeling, transposing, and reordering the bands, the
+// stacks, and the rows and columns within them.  It is the smallest
+// relabeled grid in row order over all those transforms, so it also
+// covers the eight rotations and reflections.
+func (g Grid) Canonical() Grid {
+	var cs canonSearch
+	seen := make(map[Grid]bool) // column orders that give the same grid, as with empty columns
+	for _, src := range [2]Grid{g, g.transform(1)} {
+		for _, perm := range colPerms {
+			for row := 0; row < rows; row++ {
+				for col := 0; col < cols; col++ {
+					cs.in[row][col] = src[row][perm[col]]
+				}
+			}
+			if !seen[cs.in] {
+				seen[cs.in] = true
+				cs.search(0, [10]int{}, 1)
+			}
+		}
+	}
+	return cs.best
+}
+
+// GivensHash identifies a puzzle by the SHA-256 of its canonical givens,
+// so equivalent puzzles share the same hash
+func (g Grid) GivensHash() string {
+	sum := sha256.Sum256([]byte(g.Canonical().String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// DedupPuzzles removes puzzles equivalent to an earlier one in the list
+func DedupPuzzles(puzzles []Grid) []Grid {
+	seen := make(map[string]bool)
+	var out []Grid
+	for _, p := range puzzles {
+		h := p.GivensHash()
+		if !seen[h] {
+			seen[h] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// linePerm returns a random order of the nine rows or columns that keeps
+// each band of three together, permuting the bands and the lines in them
+func linePerm(rng *rand.Rand) [9]int {
+	var perm [9]int
+	for i, band := range rng.Perm(3) {
+		for j, line := range rng.Perm(3) {
+			perm[i*3+j] = band*3 + line
+		}
+	}
+	return perm
+}
+
+// Scramble returns an equivalent puzzle made by relabeling the digits,
+// permuting the bands, stacks, and the rows and columns within them, and
+// maybe transposing, all chosen by rng
+func (g Grid) Scramble(rng *rand.Rand) Grid {
+	var label [10]int
+	for i, d := range rng.Perm(9) {
+		label[i+1] = d + 1
+	}
+	rowPerm, colPerm := linePerm(rng), linePerm(rng)
+	transpose := rng.Intn(2) == 1
+	var out Grid
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			d := label[g[rowPerm[row]][colPerm[col]]]
+			if transpose {
+				out[col][row] = d
+			} else {
+				out[row][col] = d
+			}
+		}
+	}
+	return out
+}
+
+// handleScramble returns a random puzzle equivalent to the posted one,
+// reproducible with the same seed
+func handleScramble(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"`
+		Seed   *int64 `json:"seed"` // optional seed, from the clock when absent
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Puzzle string `json:"puzzle"`
+		Seed   int64  `json:"seed"`
+	}{g.Scramble(rand.New(rand.NewSource(seed))).String(), seed})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// swapDigits relabels the grid by the permutation perm, digit d becoming perm[d-1]
+func swapDigits(g Grid, perm [9]int) Grid {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if d := g[row][col]; d != 0 {
+				g[row][col] = perm[d-1]
+			}
+		}
+	}
+	return g
+}
+
+func TestGivensHash(t *testing.T) {
+	p := mustGrid(t, testPuzzle)
+	other := mustGrid(t, testStalls)
+	tests := []struct {
+		name string
+		g    Grid
+		same bool
+	}{
+		{"identity", p, true},
+		{"transpose", p.transform(1), true},
+		{"rotation", p.transform(3), true},
+		{"reflection", p.transform(4), true},
+		{"relabeled", swapDigits(p, [9]int{9, 8, 7, 6, 5, 4, 3, 2, 1}), true},
+		{"rotated and relabeled", swapDigits(p.transform(6), [9]int{2, 3, 4, 5, 6, 7, 8, 9, 1}), true},
+		{"bands and lines reordered", func() Grid {
+			var q Grid
+			perm := [9]int{5, 3, 4, 8, 6, 7, 1, 0, 2}
+			for row := 0; row < rows; row++ {
+				for col := 0; col < cols; col++ {
+					q[row][col] = p[perm[row]][perm[(col+3)%9]]
+				}
+			}
+			return q
+		}(), true},
+		{"other puzzle", other, false},
+		{"one clue less", func() Grid { q := p; q[0][0] = 0; return q }(), false},
+	}
+	want := p.GivensHash()
+	for _, tt := range tests {
+		if got := tt.g.GivensHash(); (got == want) != tt.same {
+			t.Errorf("%s: hash %s, same as the puzzle %v, want %v", tt.name, got, got == want, tt.same)
+		}
+		if c := tt.g.Canonical(); tt.same && c != p.Canonical() {
+			t.Errorf("%s: canonical %s, want %s", tt.name, c, p.Canonical())
+		}
+	}
+}
+
+func TestDedupPuzzles(t *testing.T) {
+	p, other := mustGrid(t, testPuzzle), mustGrid(t, testStalls)
+	tests := []struct {
+		name    string
+		puzzles []Grid
+		want    []Grid
+	}{
+		{"none", nil, nil},
+		{"distinct", []Grid{p, other}, []Grid{p, other}},
+		{"equivalent", []Grid{p, other, p.transform(5), swapDigits(p, [9]int{2, 1, 3, 4, 5, 6, 7, 8, 9})}, []Grid{p, other}},
+		{"first kept", []Grid{p.transform(2), p}, []Grid{p.transform(2)}},
+	}
+	for _, tt := range tests {
+		got := DedupPuzzles(tt.puzzles)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %d puzzles, want %d", tt.name, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: puzzle %d is %s, want %s", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestScramble(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+	}{
+		{"puzzle", mustGrid(t, testPuzzle)},
+		{"stalls", mustGrid(t, testStalls)},
+		{"solution", mustGrid(t, testSolution)},
+		{"empty", Grid{}},
+	}
+	for _, tt := range tests {
+		for seed := int64(1); seed <= 5; seed++ {
+			s := tt.g.Scramble(rand.New(rand.NewSource(seed)))
+			if again := tt.g.Scramble(rand.New(rand.NewSource(seed))); again != s {
+				t.Errorf("%s seed %d: scrambled %s then %s", tt.name, seed, s, again)
+			}
+			if s.Clues() != tt.g.Clues() || s.GivensConsistent() != tt.g.GivensConsistent() {
+				t.Errorf("%s seed %d: %s has %d clues, want %d", tt.name, seed, s, s.Clues(), tt.g.Clues())
+			}
+			if got, want := len(s.Solutions(2)), len(tt.g.Solutions(2)); got != want {
+				t.Errorf("%s seed %d: %d solutions, want %d", tt.name, seed, got, want)
+			}
+			if s.GivensHash() != tt.g.GivensHash() {
+				t.Errorf("%s seed %d: %s hashes to %s, want %s", tt.name, seed, s, s.GivensHash(), tt.g.GivensHash())
+			}
+			if s.Grade() != tt.g.Grade() {
+				t.Errorf("%s seed %d: grade %s, want %s", tt.name, seed, s.Grade(), tt.g.Grade())
+			}
+		}
+	}
+}
+
+func TestHandleScramble(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"seed 1", `{"puzzle":"` + testPuzzle + `","seed":1}`, http.StatusOK},
+		{"seed 2", `{"puzzle":"` + testPuzzle + `","seed":2}`, http.StatusOK},
+		{"no seed", `{"puzzle":"` + testPuzzle + `"}`, http.StatusOK},
+		{"short puzzle", `{"puzzle":"53"}`, http.StatusBadRequest},
+		{"not json", `puzzle`, http.StatusBadRequest},
+	}
+	p := mustGrid(t, testPuzzle)
+	for _, tt := range tests {
+		var resp struct {
+			Puzzle string `json:"puzzle"`
+			Seed   int64  `json:"seed"`
+		}
+		code := callAPI(t, handleScramble, http.MethodPost, patternScramble, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if s := mustGrid(t, resp.Puzzle); s != p.Scramble(rand.New(rand.NewSource(resp.Seed))) {
+			t.Errorf("%s: puzzle %s is not the scramble for seed %d", tt.name, resp.Puzzle, resp.Seed)
+		}
+		// the seed returned reproduces the puzzle
+		var again struct {
+			Puzzle string `json:"puzzle"`
+		}
+		callAPI(t, handleScramble, http.MethodPost, patternScramble, `{"puzzle":"`+testPuzzle+`","seed":`+strconv.FormatInt(resp.Seed, 10)+`}`, &again)
+		if again.Puzzle != resp.Puzzle {
+			t.Errorf("%s: seed %d gives %s, then %s", tt.name, resp.Seed, resp.Puzzle, again.Puzzle)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ Signed claims of solved puzzles.
+ An external leaderboard can post a puzzle with its GivensHash and the
+ player's completed board to /api/claim.  When the board keeps the givens
+ and obeys the rules, the server returns a token, the hex HMAC-SHA256 of
+ the hash and the board under the -claimsecret key.  A leaderboard holding
+ the same secret checks the token with VerifyClaim, so a completion cannot
+ be forged without solving the puzzle here.
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+)
+
+const patternClaim = "/api/claim" // signed token for a solved puzzle
+
+// claimKey signs claim tokens, set from -claimsecret in main
+var claimKey []byte
+
+// initClaimKey sets the claim key from the secret, or to random bytes when
+// the secret is empty, in which case tokens are only good until restart
+func initClaimKey(secret string) {
+	if secret != "" {
+		claimKey = []byte(secret)
+		return
+	}
+	claimKey = make([]byte, 32)
+	if _, err := rand.Read(claimKey); err != nil {
+		log.Fatalf("Claim key error: %v\n", err)
+	}
+	log.Println("No -claimsecret given, claim tokens will not verify after a restart")
+}
+
+// claimToken returns the signature of a solved board for the puzzle hash
+func claimToken(hash string, board Grid) string {
+	mac := hmac.New(sha256.New, claimKey)
+	mac.Write([]byte(hash + ":" + board.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyClaim reports whether the token was issued for the board as the
+// solution of the puzzle with the hash
+func VerifyClaim(hash string, board Grid, token string) bool {
+	sig, err := hex.DecodeString(token)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(claimToken(hash, board))
+	return hmac.Equal(sig, want)
+}
+
+// handleClaim checks that the board solves the puzzle with the given hash
+// and returns a token proving the win
+func handleClaim(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Hash   string `json:"hash"`
+		Puzzle string `json:"puzzle"`
+		Board  string `json:"board"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	givens, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "puzzle: "+err.Error())
+		return
+	}
+	board, err := ParseGrid(req.Board)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "board: "+err.Error())
+		return
+	}
+	switch {
+	case givens.GivensHash() != req.Hash:
+		writeJSONError(w, http.StatusBadRequest, "hash does not match the puzzle")
+		return
+	case board.Clues() < rows*cols:
+		writeJSONError(w, http.StatusUnprocessableEntity, "board is not complete")
+		return
+	case !board.RespectsGivens(givens):
+		writeJSONError(w, http.StatusUnprocessableEntity, "board changes the givens")
+		return
+	case !board.GivensConsistent():
+		writeJSONError(w, http.StatusUnprocessableEntity, "board breaks the rules")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Hash  string `json:"hash"`
+		Board string `json:"board"`
+		Token string `json:"token"`
+	}{req.Hash, board.String(), claimToken(req.Hash, board)})
+}
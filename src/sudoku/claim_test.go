@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHandleClaim(t *testing.T) {
+	defer func(k []byte) { claimKey = k }(claimKey)
+	initClaimKey("test secret")
+	hash := mustGrid(t, testPuzzle).GivensHash()
+	changed := []byte(testSolution)
+	changed[0], changed[2] = changed[2], changed[0] // swaps a given with an entry
+	broken := []byte(testSolution)
+	broken[2], broken[3] = broken[3], broken[2] // entries only, row keeps its digits
+	tests := []struct {
+		name  string
+		hash  string
+		board string
+		code  int
+	}{
+		{"solved", hash, testSolution, http.StatusOK},
+		{"wrong hash", "00" + hash[2:], testSolution, http.StatusBadRequest},
+		{"incomplete", hash, testPuzzle, http.StatusUnprocessableEntity},
+		{"changes a given", hash, string(changed), http.StatusUnprocessableEntity},
+		{"breaks the rules", hash, string(broken), http.StatusUnprocessableEntity},
+		{"short board", hash, "53", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Hash  string `json:"hash"`
+			Board string `json:"board"`
+			Token string `json:"token"`
+		}
+		body := `{"hash":"` + tt.hash + `","puzzle":"` + testPuzzle + `","board":"` + tt.board + `"}`
+		code := callAPI(t, handleClaim, http.MethodPost, patternClaim, body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code == http.StatusOK && !VerifyClaim(tt.hash, mustGrid(t, tt.board), resp.Token) {
+			t.Errorf("%s: token %q does not verify", tt.name, resp.Token)
+		}
+	}
+}
+
+func TestVerifyClaim(t *testing.T) {
+	defer func(k []byte) { claimKey = k }(claimKey)
+	initClaimKey("test secret")
+	sol := mustGrid(t, testSolution)
+	hash := mustGrid(t, testPuzzle).GivensHash()
+	token := claimToken(hash, sol)
+	other := sol.transform(1)
+	tests := []struct {
+		name  string
+		hash  string
+		board Grid
+		token string
+		want  bool
+	}{
+		{"issued", hash, sol, token, true},
+		{"other board", hash, other, token, false},
+		{"other hash", other.GivensHash(), sol, token, false},
+		{"not hex", hash, sol, "zz" + token[2:], false},
+		{"truncated", hash, sol, token[:32], false},
+		{"empty", hash, sol, "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyClaim(tt.hash, tt.board, tt.token); got != tt.want {
+			t.Errorf("%s: VerifyClaim = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// a token from another key does not verify
+	initClaimKey("other secret")
+	if VerifyClaim(hash, sol, token) {
+		t.Errorf("token verifies under another key")
+	}
+}
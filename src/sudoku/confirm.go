@@ -0,0 +1,105 @@
+/*
+ Confirmation before solving over the player's entries.
+ Solving fills the board from the givens alone, so entries that break the
+ rules would be thrown away without the player seeing why.  The first
+ solve of such a board returns it unchanged with the conflicts marked and
+ a one-time token in the solvetoken field; solving again with the token
+ and the same board goes ahead.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const confirmTTL = 10 * time.Minute // how long a solve confirmation stays valid
+
+// solveConfirms maps confirm tokens to the board they were issued for
+var solveConfirms = newSessionStore(confirmTTL, time.Minute)
+
+// conflictCells returns the cells of the board, givens excepted, holding a
+// digit that appears again in one of their units, in reading order.
+// Values outside 1-9 are skipped.
+func conflictCells(givens, board Grid) [][2]int {
+	var bad [rows][cols]bool
+	for _, u := range rules.Units() {
+		var seen [10]int
+		for _, rc := range u.Cells {
+			if d := board[rc[0]][rc[1]]; validDigit(d) {
+				seen[d]++
+			}
+		}
+		for _, rc := range u.Cells {
+			if d := board[rc[0]][rc[1]]; validDigit(d) && seen[d] > 1 && givens[rc[0]][rc[1]] == 0 {
+				bad[rc[0]][rc[1]] = true
+			}
+		}
+	}
+	var cells [][2]int
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if bad[row][col] {
+				cells = append(cells, [2]int{row, col})
+			}
+		}
+	}
+	return cells
+}
+
+// confirmSolveSubmit answers a solve submission of a board whose entries
+// break the rules with the board and a confirm token, unless the request
+// carries a token issued for this board.  It returns false, writing
+// nothing, when the solve can go ahead.
+func confirmSolveSubmit(w http.ResponseWriter, r *http.Request) bool {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return true
+	}
+	cells := conflictCells(givens, board)
+	if len(cells) == 0 {
+		return false
+	}
+	if token := r.FormValue("solvetoken"); len(token) > 0 {
+		if v, ok := solveConfirms.Get(token); ok && v == board.String() {
+			solveConfirms.Delete(token)
+			return false
+		}
+	}
+	token, err := newSessionID()
+	if err != nil {
+		log.Printf("Solve confirm token error: %v\n", err)
+		http.Error(w, "cannot confirm the solve", http.StatusInternalServerError)
+		return true
+	}
+	solveConfirms.Set(token, board.String())
+
+	// Keep the player's entries and mark the ones in conflict
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+	sudoku.Constraints = r.FormValue("constraints")
+	sudoku.SolveToken = token
+	for _, rc := range cells {
+		name := fmt.Sprintf("%d_%d_%d", rc[0], rc[1], (rc[0]/3)*3+rc[1]/3)
+		cell := sudoku.Grid[name]
+		cell.Invalid = "invalid"
+		sudoku.Grid[name] = cell
+	}
+	verb := "break"
+	if len(cells) == 1 {
+		verb = "breaks"
+	}
+	sudoku.Status.Message = fmt.Sprintf("Status: %d of your entries %s the rules, starting with %s, submit Solve again to replace them",
+		len(cells), verb, cellName(cells[0][0], cells[0][1]))
+	sudoku.Status.State = "invalidstatus"
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestConflictCells(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	dup := puzzle
+	dup[0][2] = 5 // 5 is the given at r1c1
+	pair := puzzle
+	pair[0][2], pair[0][3] = 4, 4
+	tampered := puzzle
+	tampered[0][2], tampered[0][3] = 10, -1
+	tests := []struct {
+		name  string
+		board Grid
+		want  [][2]int
+	}{
+		{"puzzle", puzzle, nil},
+		{"entry repeats a given", dup, [][2]int{{0, 2}}},
+		{"entries repeat each other", pair, [][2]int{{0, 2}, {0, 3}}},
+		{"out of range", tampered, nil},
+	}
+	for _, tt := range tests {
+		got := conflictCells(puzzle, tt.board)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: conflictCells = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: conflictCells = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+var solveTokenRe = regexp.MustCompile(`name="solvetoken" value="([0-9a-f]*)"`)
+
+func TestConfirmSolve(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	board := puzzle
+	board[0][2] = 5
+	other := puzzle
+	other[0][2] = 3
+
+	// A board breaking the rules gets a prompt and a token
+	rec := postForm("solve", boardForm(puzzle, board))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("solve: status code %d, want 200", rec.Code)
+	}
+	m := solveTokenRe.FindStringSubmatch(rec.Body.String())
+	if m == nil || m[1] == "" {
+		t.Fatal("solve: page has no confirm token")
+	}
+	token := m[1]
+	if !strings.Contains(rec.Body.String(), `name="0_2_0" value="5"`) {
+		t.Error("solve: prompt does not keep the entries")
+	}
+
+	tests := []struct {
+		name    string
+		board   Grid
+		token   string
+		confirm bool // the page asks again
+	}{
+		{"no conflicts", puzzle, "", false},
+		{"other board", other, token, true},
+		{"wrong token", board, "00", true},
+		{"token", board, token, false},
+		{"token used", board, token, true},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, tt.board)
+		form.Set("solvetoken", tt.token)
+		rec := postForm("solve", form)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, rec.Code)
+			continue
+		}
+		m := solveTokenRe.FindStringSubmatch(rec.Body.String())
+		if asked := m != nil && m[1] != ""; asked != tt.confirm {
+			t.Errorf("%s: asked for confirmation %v, want %v", tt.name, asked, tt.confirm)
+		}
+		if solved := strings.Contains(rec.Body.String(), `value="Status: Solved`); solved == tt.confirm {
+			t.Errorf("%s: solved %v, want %v", tt.name, solved, !tt.confirm)
+		}
+	}
+}
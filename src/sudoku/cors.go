@@ -0,0 +1,54 @@
+/*
+ Cross-origin access to the JSON API.
+ Browser frontends served from other origins may call the /api/ endpoints
+ when their origin is listed in the -cors option.  By default no CORS
+ headers are sent, so only pages from this server can use the API.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+const patternAPI = "/api/" // prefix of the JSON API endpoints
+
+// allowedOrigin reports whether the origin is listed in the -cors option,
+// where * allows every origin
+func allowedOrigin(origin string) bool {
+	for _, o := range strings.Split(*corsOrigins, ",") {
+		if o = strings.TrimSpace(o); o == "*" || (o != "" && o == origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS adds CORS headers to the responses of the JSON API for allowed
+// origins and answers their preflight OPTIONS requests
+func withCORS(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !strings.HasPrefix(r.URL.Path, patternAPI) || origin == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := allowedOrigin(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", "X-Sudoku-Difficulty, X-Sudoku-Clues")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+				w.Header().Set("Access-Control-Max-Age", "600")
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
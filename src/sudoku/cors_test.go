@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedOrigin(t *testing.T) {
+	defer func(s string) { *corsOrigins = s }(*corsOrigins)
+	tests := []struct {
+		option string
+		origin string
+		want   bool
+	}{
+		{"", "https://a.example", false},
+		{"https://a.example", "https://a.example", true},
+		{"https://a.example, https://b.example", "https://b.example", true},
+		{"https://a.example", "https://b.example", false},
+		{"https://a.example,", "", false},
+		{"*", "https://b.example", true},
+	}
+	for _, tt := range tests {
+		*corsOrigins = tt.option
+		if got := allowedOrigin(tt.origin); got != tt.want {
+			t.Errorf("-cors %q: allowedOrigin(%q) = %v, want %v", tt.option, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	defer func(s string) { *corsOrigins = s }(*corsOrigins)
+	*corsOrigins = "https://a.example"
+	h := withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		origin    string
+		preflight bool
+		code      int
+		allow     string // Access-Control-Allow-Origin
+	}{
+		{"allowed", http.MethodPost, patternGrade, "https://a.example", false, http.StatusTeapot, "https://a.example"},
+		{"other origin", http.MethodPost, patternGrade, "https://b.example", false, http.StatusTeapot, ""},
+		{"same origin", http.MethodPost, patternGrade, "", false, http.StatusTeapot, ""},
+		{"not the API", http.MethodPost, patternSubmit, "https://a.example", false, http.StatusTeapot, ""},
+		{"preflight", http.MethodOptions, patternGrade, "https://a.example", true, http.StatusNoContent, "https://a.example"},
+		{"other preflight", http.MethodOptions, patternGrade, "https://b.example", true, http.StatusNoContent, ""},
+		{"options", http.MethodOptions, patternGrade, "https://a.example", false, http.StatusTeapot, "https://a.example"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if tt.preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+			t.Errorf("%s: Access-Control-Allow-Origin %q, want %q", tt.name, got, tt.allow)
+		}
+		methods := rec.Header().Get("Access-Control-Allow-Methods")
+		if (methods != "") != (tt.preflight && tt.allow != "") {
+			t.Errorf("%s: Access-Control-Allow-Methods %q", tt.name, methods)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+/*
+ Generation of graded puzzles.
+ A random complete grid is reduced clue by clue while the puzzle keeps a
+ unique solution and grades no harder than the target difficulty.  The
+ result is kept only when it grades exactly at the target, otherwise a
+ new grid is tried, up to maxGenAttempts times.  A request for at most
+ some number of clues reduces grids the same way until one is sparse
+ enough, and when none is within the attempts the sparsest is returned
+ with a status saying it fell short.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	patternLadder  = "/api/ladder" // one puzzle per difficulty
+	patternNew     = "/api/new"    // puzzle from a random or chosen solution
+	maxGenAttempts = 50            // grids tried for one graded puzzle
+	minClues       = 17            // fewest clues of any uniquely solvable puzzle
+)
+
+// Generated is a puzzle produced for a difficulty
+type Generated struct {
+	Difficulty string `json:"difficulty"`
+	Puzzle     string `json:"puzzle"`
+	Clues      int    `json:"clues"`
+	solution   Grid
+}
+
+// generate makes a uniquely solvable puzzle that grades exactly at level,
+// returning false when maxGenAttempts grids all miss the difficulty or
+// the context is cancelled
+func generate(ctx context.Context, rng *rand.Rand, level int) (Generated, bool) {
+	begin := time.Now()
+	for attempt := 1; attempt <= maxGenAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return Generated{}, false
+		}
+		sol := RandomSolution(rng)
+		p := sol.MinimizeWithin(rng, level)
+		if p.gradeLevel() != level {
+			continue
+		}
+		recordGeneration(difficultyNames[level], attempt, time.Since(begin))
+		gen := Generated{Difficulty: difficultyNames[level], Puzzle: p.String(), Clues: p.Clues(), solution: sol}
+		if *checkGrade {
+			checkGenerated(gen.Puzzle, gen.Difficulty)
+		}
+		return gen, true
+	}
+	return Generated{}, false
+}
+
+// sparsest reduces solutions with reduce until a puzzle has at most target
+// clues, trying a new solution from next each time, up to maxGenAttempts
+// times or until the context is cancelled.  It returns the sparsest puzzle,
+// its solution, and the attempts made.
+func sparsest(ctx context.Context, next func() Grid, reduce func(Grid) Grid, target int) (p, sol Grid, attempts int) {
+	for attempts < maxGenAttempts && (attempts == 0 || ctx.Err() == nil) {
+		attempts++
+		s := next()
+		q := reduce(s)
+		if attempts == 1 || q.Clues() < p.Clues() {
+			p, sol = q, s
+		}
+		if p.Clues() <= target {
+			break
+		}
+	}
+	return p, sol, attempts
+}
+
+// seedParam returns the seed query parameter, or a seed from the clock when absent
+func seedParam(r *http.Request) (int64, error) {
+	if s := r.URL.Query().Get("seed"); len(s) > 0 {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	return time.Now().UnixNano(), nil
+}
+
+// ladderRungs makes one puzzle for each difficulty with gen, each with a
+// different solution.  A rung whose puzzles repeat a solution already in
+// the ladder is retried up to maxGenAttempts times.  It returns the rungs
+// made and false when a rung could not be made.
+func ladderRungs(gen func(level int) (Generated, bool)) ([]Generated, bool) {
+	ladder := make([]Generated, 0, len(difficultyNames))
+	used := make(map[string]bool) // canonical solutions already in the ladder
+	for level := range difficultyNames {
+		for attempt := 1; ; attempt++ {
+			if attempt > maxGenAttempts {
+				return ladder, false
+			}
+			g, ok := gen(level)
+			if !ok {
+				return ladder, false
+			}
+			if h := g.solution.GivensHash(); !used[h] {
+				used[h] = true
+				ladder = append(ladder, g)
+				break
+			}
+		}
+	}
+	return ladder, true
+}
+
+// handleLadder returns one puzzle for each difficulty from easy to expert,
+// each with a different solution
+func handleLadder(w http.ResponseWriter, r *http.Request) {
+	seed, err := seedParam(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "seed must be an integer")
+		return
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	ladder, ok := ladderRungs(func(level int) (Generated, bool) {
+		return generate(r.Context(), rng, level)
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	if !ok {
+		writeJSONError(w, http.StatusServiceUnavailable, "could not generate a "+difficultyNames[len(ladder)]+" puzzle")
+		return
+	}
+	writeJSON(w, http.StatusOK, ladder)
+}
+
+// handleNew makes a uniquely solvable puzzle by removing clues from the
+// base solved grid, or from a random one when base is empty.  With a
+// difficulty the puzzle grades no harder than it, otherwise it is minimal.
+// With maxClues removal is retried until the puzzle has at most that many
+// clues, falling back to the sparsest puzzle found.  Balanced leaves
+// at least one clue in every box.
+func handleNew(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Base       string `json:"base"`       // optional complete grid to blank
+		Difficulty string `json:"difficulty"` // optional hardest grade allowed
+		Seed       *int64 `json:"seed"`       // optional seed, from the clock when absent
+		MaxClues   int    `json:"maxClues"`   // optional sparsity target, 17-81
+		Balanced   bool   `json:"balanced"`   // keep at least one clue in every box
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	level := expert
+	if len(req.Difficulty) > 0 {
+		var ok bool
+		if level, ok = parseDifficulty(req.Difficulty); !ok {
+			writeJSONError(w, http.StatusBadRequest, "difficulty must be easy, medium, hard, or expert")
+			return
+		}
+	}
+	if req.MaxClues != 0 && (req.MaxClues < minClues || req.MaxClues > rows*cols) {
+		writeJSONError(w, http.StatusBadRequest, "maxClues must be between "+strconv.Itoa(minClues)+" and "+strconv.Itoa(rows*cols))
+		return
+	}
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	var base Grid
+	if len(req.Base) > 0 {
+		var err error
+		if base, err = ParseGrid(req.Base); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if base.Clues() != rows*cols || !base.GivensConsistent() {
+			writeJSONError(w, http.StatusBadRequest, "base must be a complete valid solution")
+			return
+		}
+	}
+	next := func() Grid {
+		if len(req.Base) > 0 {
+			return base
+		}
+		return RandomSolution(rng)
+	}
+	reduce := func(sol Grid) Grid { return sol.Minimize(rng) }
+	switch {
+	case req.Balanced:
+		reduce = func(sol Grid) Grid { return sol.MinimizeBalanced(rng, level) }
+	case len(req.Difficulty) > 0:
+		reduce = func(sol Grid) Grid { return sol.MinimizeWithin(rng, level) }
+	}
+
+	begin := time.Now()
+	target := rows * cols
+	if req.MaxClues != 0 {
+		target = req.MaxClues
+	}
+	p, sol, attempts := sparsest(r.Context(), next, reduce, target)
+	if len(req.Difficulty) > 0 {
+		recordGeneration(difficultyNames[level], attempts, time.Since(begin))
+	}
+	var status string
+	if p.Clues() > target {
+		status = fmt.Sprintf("fell short of %d clues in %d attempts, sparsest puzzle found has %d", target, attempts, p.Clues())
+	}
+	setPuzzleHeaders(w, p)
+	writeJSON(w, http.StatusOK, struct {
+		Puzzle   string `json:"puzzle"`
+		Solution string `json:"solution"`
+		Clues    int    `json:"clues"`
+		Grade    string `json:"grade"`
+		Seed     int64  `json:"seed"`
+		Status   string `json:"status,omitempty"` // set when maxClues was not reached
+	}{p.String(), sol.String(), p.Clues(), p.Grade(), seed, status})
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerateCancelled(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		ok   bool
+	}{
+		{"background", context.Background(), true},
+		{"cancelled", cancelled, false},
+	}
+	for _, tt := range tests {
+		gen, ok := generate(tt.ctx, rand.New(rand.NewSource(1)), easy)
+		if ok != tt.ok || ok && gen.Difficulty != "easy" {
+			t.Errorf("%s: generate = %+v, %v, want ok %v", tt.name, gen, ok, tt.ok)
+		}
+	}
+
+	// a cancelled ladder request writes nothing
+	req := httptest.NewRequest(http.MethodGet, patternLadder+"?seed=1", nil).WithContext(cancelled)
+	rec := httptest.NewRecorder()
+	handleLadder(rec, req)
+	if rec.Body.Len() != 0 {
+		t.Errorf("cancelled ladder wrote %q", rec.Body.String())
+	}
+}
+
+func TestSparsest(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	solution := mustGrid(t, testSolution)
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		target   int
+		attempts int
+		clues    int
+	}{
+		{"first reaches target", context.Background(), 30, 1, 30},
+		{"third reaches target", context.Background(), 28, 3, 28},
+		{"falls short", context.Background(), minClues, maxGenAttempts, 28},
+		{"cancelled", cancelled, minClues, 1, 30},
+	}
+	for _, tt := range tests {
+		// each reduction blanks one more clue, to at most two fewer than the first
+		calls := 0
+		next := func() Grid { return solution }
+		reduce := func(Grid) Grid {
+			p := puzzle
+			for i := 0; i < calls && i < 2; i++ {
+				p[0][i] = 0
+			}
+			calls++
+			return p
+		}
+		p, sol, attempts := sparsest(tt.ctx, next, reduce, tt.target)
+		if attempts != tt.attempts || p.Clues() != tt.clues || sol != solution {
+			t.Errorf("%s: %d attempts for %d clues, want %d for %d", tt.name, attempts, p.Clues(), tt.attempts, tt.clues)
+		}
+	}
+}
+
+func TestHandleLadder(t *testing.T) {
+	tests := []struct {
+		query string
+		code  int
+	}{
+		{"?seed=1", http.StatusOK},
+		{"?seed=2", http.StatusOK},
+		{"?seed=x", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var ladder []Generated
+		code := callAPI(t, handleLadder, http.MethodGet, patternLadder+tt.query, "", &ladder)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.query, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if len(ladder) != len(difficultyNames) {
+			t.Errorf("%s: %d puzzles, want %d", tt.query, len(ladder), len(difficultyNames))
+			continue
+		}
+		solutions := make(map[string]bool)
+		for level, gen := range ladder {
+			p := mustGrid(t, gen.Puzzle)
+			if gen.Difficulty != difficultyNames[level] || p.Grade() != gen.Difficulty || p.Clues() != gen.Clues {
+				t.Errorf("%s: rung %d is %s with %d clues, grades %s", tt.query, level, gen.Difficulty, gen.Clues, p.Grade())
+			}
+			sols := p.Solutions(2)
+			if len(sols) != 1 {
+				t.Errorf("%s: %s puzzle has %d solutions", tt.query, gen.Difficulty, len(sols))
+				continue
+			}
+			solutions[sols[0].GivensHash()] = true
+		}
+		if len(solutions) != len(ladder) {
+			t.Errorf("%s: rungs share solutions", tt.query)
+		}
+	}
+}
+
+func TestLadderRungs(t *testing.T) {
+	var sols []Grid
+	for seed := int64(1); seed <= 4; seed++ {
+		sols = append(sols, RandomSolution(rand.New(rand.NewSource(seed))))
+	}
+	sol := sols[0]
+	tests := []struct {
+		name  string
+		sols  []Grid // solutions of the puzzles generated, repeating the last
+		fail  int    // level whose puzzles fail to generate, -1 for none
+		rungs int
+		ok    bool
+		calls int
+	}{
+		{"distinct", sols, -1, 4, true, 4},
+		{"equivalent repeat", []Grid{sol, swapDigits(sol.transform(1), [9]int{2, 1, 3, 4, 5, 6, 7, 8, 9}), sols[1], sols[2], sols[3]}, -1, 4, true, 5},
+		{"always the same", []Grid{sol}, -1, 1, false, 1 + maxGenAttempts},
+		{"generation fails", sols, hard, 2, false, 3},
+	}
+	for _, tt := range tests {
+		calls := 0
+		ladder, ok := ladderRungs(func(level int) (Generated, bool) {
+			calls++
+			if level == tt.fail {
+				return Generated{}, false
+			}
+			i := calls - 1
+			if i >= len(tt.sols) {
+				i = len(tt.sols) - 1
+			}
+			return Generated{Difficulty: difficultyNames[level], solution: tt.sols[i]}, true
+		})
+		if len(ladder) != tt.rungs || ok != tt.ok || calls != tt.calls {
+			t.Errorf("%s: %d rungs, ok %v, %d calls, want %d, %v, %d", tt.name, len(ladder), ok, calls, tt.rungs, tt.ok, tt.calls)
+		}
+	}
+}
+
+// newResponse is the response of handleNew
+type newResponse struct {
+	Puzzle   string `json:"puzzle"`
+	Solution string `json:"solution"`
+	Clues    int    `json:"clues"`
+	Grade    string `json:"grade"`
+	Seed     int64  `json:"seed"`
+	Status   string `json:"status"`
+}
+
+func TestHandleNewBase(t *testing.T) {
+	broken := []byte(testSolution)
+	broken[0], broken[1] = broken[1], broken[0]
+	tests := []struct {
+		name  string
+		body  string
+		code  int
+		base  bool   // the solution is testSolution
+		grade string // any grade when empty
+	}{
+		{"base", `{"base":"` + testSolution + `","seed":1}`, http.StatusOK, true, ""},
+		{"base easy", `{"base":"` + testSolution + `","seed":2,"difficulty":"easy"}`, http.StatusOK, true, "easy"},
+		{"random", `{"seed":3}`, http.StatusOK, false, ""},
+		{"incomplete base", `{"base":"` + testPuzzle + `"}`, http.StatusBadRequest, false, ""},
+		{"base breaks the rules", `{"base":"` + string(broken) + `"}`, http.StatusBadRequest, false, ""},
+		{"short base", `{"base":"53"}`, http.StatusBadRequest, false, ""},
+	}
+	for _, tt := range tests {
+		var resp newResponse
+		code := callAPI(t, handleNew, http.MethodPost, patternNew, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		p, sol := mustGrid(t, resp.Puzzle), mustGrid(t, resp.Solution)
+		sols := p.Solutions(2)
+		if len(sols) != 1 || sols[0] != sol || resp.Clues != p.Clues() || resp.Grade != p.Grade() {
+			t.Errorf("%s: response %+v", tt.name, resp)
+		}
+		if tt.base && resp.Solution != testSolution {
+			t.Errorf("%s: solution %s, want the base", tt.name, resp.Solution)
+		}
+		if tt.grade != "" && resp.Grade != tt.grade {
+			t.Errorf("%s: grade %s, want %s", tt.name, resp.Grade, tt.grade)
+		}
+	}
+}
+
+func TestHandleNewMaxClues(t *testing.T) {
+	base := `"base":"` + testSolution + `","seed":1`
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		short  bool // the status reports falling short
+		target int
+	}{
+		{"any clues", `{` + base + `,"maxClues":81}`, http.StatusOK, false, 81},
+		{"below any puzzle", `{` + base + `,"maxClues":17}`, http.StatusOK, true, 17},
+		{"too few", `{` + base + `,"maxClues":16}`, http.StatusBadRequest, false, 0},
+		{"too many", `{` + base + `,"maxClues":82}`, http.StatusBadRequest, false, 0},
+	}
+	for _, tt := range tests {
+		var resp newResponse
+		code := callAPI(t, handleNew, http.MethodPost, patternNew, tt.body, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if sols := mustGrid(t, resp.Puzzle).Solutions(2); len(sols) != 1 || resp.Solution != testSolution {
+			t.Errorf("%s: puzzle %s has %d solutions", tt.name, resp.Puzzle, len(sols))
+		}
+		if short := resp.Status != ""; short != tt.short || short != (resp.Clues > tt.target) {
+			t.Errorf("%s: %d clues with status %q, want short %v", tt.name, resp.Clues, resp.Status, tt.short)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ Puzzle generation statistics.
+ Each generated puzzle records how long it took and how many attempts it
+ needed under a label for its difficulty, the difficulty name for graded
+ generation or the blank cell count for the new option.
+*/
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const patternGenStats = "/api/genstats" // generation statistics per difficulty
+
+// genStat accumulates the generation of puzzles of one difficulty
+type genStat struct {
+	puzzles  int
+	attempts int
+	elapsed  time.Duration
+}
+
+// genStats holds the statistics of every difficulty generated so far
+var genStats = struct {
+	sync.Mutex
+	m map[string]*genStat
+}{m: make(map[string]*genStat)}
+
+// recordGeneration adds one generated puzzle to the statistics of its difficulty
+func recordGeneration(label string, attempts int, elapsed time.Duration) {
+	genStats.Lock()
+	defer genStats.Unlock()
+	st, ok := genStats.m[label]
+	if !ok {
+		st = &genStat{}
+		genStats.m[label] = st
+	}
+	st.puzzles++
+	st.attempts += attempts
+	st.elapsed += elapsed
+}
+
+// handleGenStats returns the puzzle count and average attempts and time per difficulty
+func handleGenStats(w http.ResponseWriter, r *http.Request) {
+	type stat struct {
+		Puzzles     int     `json:"puzzles"`
+		AvgAttempts float64 `json:"avgAttempts"`
+		AvgMillis   float64 `json:"avgMillis"`
+	}
+	resp := make(map[string]stat)
+	genStats.Lock()
+	for label, st := range genStats.m {
+		n := float64(st.puzzles)
+		resp[label] = stat{
+			Puzzles:     st.puzzles,
+			AvgAttempts: float64(st.attempts) / n,
+			AvgMillis:   float64(st.elapsed) / float64(time.Millisecond) / n,
+		}
+	}
+	genStats.Unlock()
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGenStats(t *testing.T) {
+	records := []struct {
+		label    string
+		attempts int
+		elapsed  time.Duration
+	}{
+		{"test-a", 1, 10 * time.Millisecond},
+		{"test-a", 3, 30 * time.Millisecond},
+		{"test-b", 2, 5 * time.Millisecond},
+	}
+	for _, rec := range records {
+		recordGeneration(rec.label, rec.attempts, rec.elapsed)
+	}
+	var resp map[string]struct {
+		Puzzles     int     `json:"puzzles"`
+		AvgAttempts float64 `json:"avgAttempts"`
+		AvgMillis   float64 `json:"avgMillis"`
+	}
+	if code := callAPI(t, handleGenStats, http.MethodGet, patternGenStats, "", &resp); code != http.StatusOK {
+		t.Fatalf("status code %d, want 200", code)
+	}
+	tests := []struct {
+		label       string
+		puzzles     int
+		avgAttempts float64
+		avgMillis   float64
+	}{
+		{"test-a", 2, 2, 20},
+		{"test-b", 1, 2, 5},
+	}
+	for _, tt := range tests {
+		st := resp[tt.label]
+		if st.Puzzles != tt.puzzles || st.AvgAttempts != tt.avgAttempts || st.AvgMillis != tt.avgMillis {
+			t.Errorf("%s: %+v, want %d puzzles, %v attempts, %v ms", tt.label, st, tt.puzzles, tt.avgAttempts, tt.avgMillis)
+		}
+	}
+}
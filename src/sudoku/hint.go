@@ -0,0 +1,114 @@
+/*
+ Hints for the player.
+ A hint is worked out by the logical solver from the givens and the
+ player's entries, so it always points at a cell that can be deduced
+ from the board as it stands rather than from the hidden solution.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// hintSudokuSubmit processes the Sudoku form submission for the hint option.
+// The locate hint type highlights the next deducible cell without filling it,
+// the singles hint type highlights every cell with a single candidate, and
+// the challenge hint type highlights the cell needing the hardest technique.
+func hintSudokuSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+
+	switch hinttype := r.FormValue("hinttype"); {
+	case hinttype != "locate" && hinttype != "singles" && hinttype != "challenge":
+		sudoku.Status.Message = fmt.Sprintf("Status: Unknown hint type %q", hinttype)
+		sudoku.Status.State = "invalidstatus"
+	case !board.GivensConsistent():
+		sudoku.Status.Message = "Status: No hint, fix the invalid entries first"
+		sudoku.Status.State = "invalidstatus"
+	case hinttype == "singles":
+		singles := board.NakedSingles()
+		for _, c := range singles {
+			subgrid := (c.Row/3)*3 + c.Col/3
+			name := fmt.Sprintf("%d_%d_%d", c.Row, c.Col, subgrid)
+			cell := sudoku.Grid[name]
+			cell.Hint = true
+			sudoku.Grid[name] = cell
+		}
+		sudoku.Status.Message = fmt.Sprintf("Status: Hint, %d highlighted cells have a single candidate", len(singles))
+	default:
+		next := board.NextPlacement
+		if hinttype == "challenge" {
+			next = board.hardestPlacement
+		}
+		step, ok := next()
+		if !ok {
+			sudoku.Status.Message = "Status: No hint, logic cannot place another digit"
+			break
+		}
+		subgrid := (step.Placement.Row/3)*3 + step.Placement.Col/3
+		name := fmt.Sprintf("%d_%d_%d", step.Placement.Row, step.Placement.Col, subgrid)
+		cell := sudoku.Grid[name]
+		cell.Hint = true
+		sudoku.Grid[name] = cell
+		sudoku.Status.Message = "Status: Hint, the highlighted cell can be deduced by " + step.Technique
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// forcedSudokuSubmit processes the Sudoku form submission for the forced option.
+// Every cell the logical techniques can deduce is filled, repeatedly, and the
+// cells that need harder reasoning or guessing are left empty.
+func forcedSudokuSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+
+	if !board.GivensConsistent() {
+		sudoku.Status.Message = "Status: Cannot fill forced cells, fix the invalid entries first"
+		sudoku.Status.State = "invalidstatus"
+	} else {
+		_, final := board.Explain()
+		sudoku = newPuzzle(givens, final)
+		sudoku.MoveLog = r.FormValue("movelog")
+		filled := 0
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				if board[row][col] == 0 && final[row][col] != 0 {
+					subgrid := (row/3)*3 + col/3
+					name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+					cell := sudoku.Grid[name]
+					cell.Diff = "solved"
+					sudoku.Grid[name] = cell
+					filled++
+				}
+			}
+		}
+		if left := rows*cols - final.Clues(); left > 0 {
+			sudoku.Status.Message = fmt.Sprintf("Status: Filled %d forced cells, %d cells need harder reasoning", filled, left)
+		} else {
+			sudoku.Status.Message = fmt.Sprintf("Status: Filled %d forced cells, logic solves the puzzle", filled)
+			sudoku.Status.State = "solvedstatus"
+		}
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNextPlacement(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+		ok   bool
+		want Clue
+	}{
+		{"puzzle", mustGrid(t, testPuzzle), true, Clue{4, 4, 5}},
+		{"solved", mustGrid(t, testSolution), false, Clue{}},
+	}
+	for _, tt := range tests {
+		step, ok := tt.g.NextPlacement()
+		if ok != tt.ok || ok && *step.Placement != tt.want {
+			t.Errorf("%s: NextPlacement = %+v, %v, want %+v, %v", tt.name, step.Placement, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHintLocate(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	conflict := puzzle
+	conflict[0][2] = 5
+	tests := []struct {
+		name     string
+		board    Grid
+		hinttype string
+		status   string
+		hints    int
+	}{
+		{"locate", puzzle, "locate", "Status: Hint, the highlighted cell can be deduced by Naked single", 1},
+		{"solved", mustGrid(t, testSolution), "locate", "Status: No hint, logic cannot place another digit", 0},
+		{"conflict", conflict, "locate", "Status: No hint, fix the invalid entries first", 0},
+		{"challenge", puzzle, "challenge", "Status: Hint, the highlighted cell can be deduced by Hidden single", 1},
+		{"solved challenge", mustGrid(t, testSolution), "challenge", "Status: No hint, logic cannot place another digit", 0},
+		{"unknown type", puzzle, "reveal", "Status: Unknown hint type &#34;reveal&#34;", 0},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, tt.board)
+		form.Set("hinttype", tt.hinttype)
+		rec := postForm("hint", form)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `value="`+tt.status+`"`) {
+			t.Errorf("%s: status code %d, page does not show %q", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if n := strings.Count(body, ` hint"`); n != tt.hints {
+			t.Errorf("%s: %d cells highlighted, want %d", tt.name, n, tt.hints)
+		}
+	}
+
+	// the hint shows where, not what
+	form := boardForm(puzzle, puzzle)
+	form.Set("hinttype", "locate")
+	if body := postForm("hint", form).Body.String(); !strings.Contains(body, `name="4_4_4" value="" class="valid hint"`) {
+		t.Errorf("hinted cell R5C5 is not highlighted empty")
+	}
+}
+
+func TestHintSingles(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	holes := mustGrid(t, testSolution)
+	holes[4][4], holes[8][0] = 0, 0
+	tests := []struct {
+		name   string
+		givens Grid
+		board  Grid
+	}{
+		{"puzzle", puzzle, puzzle},
+		{"two holes", puzzle, holes},
+		{"solved", puzzle, mustGrid(t, testSolution)},
+	}
+	for _, tt := range tests {
+		form := boardForm(tt.givens, tt.board)
+		form.Set("hinttype", "singles")
+		rec := postForm("hint", form)
+		body := rec.Body.String()
+		singles := tt.board.NakedSingles()
+		status := fmt.Sprintf("Status: Hint, %d highlighted cells have a single candidate", len(singles))
+		if rec.Code != http.StatusOK || !strings.Contains(body, `value="`+status+`"`) {
+			t.Errorf("%s: status code %d, page does not show %q", tt.name, rec.Code, status)
+			continue
+		}
+		if n := strings.Count(body, ` hint"`); n != len(singles) {
+			t.Errorf("%s: %d cells highlighted, want %d", tt.name, n, len(singles))
+		}
+		for _, c := range singles {
+			name := fmt.Sprintf("%d_%d_%d", c.Row, c.Col, (c.Row/3)*3+c.Col/3)
+			if !strings.Contains(body, `name="`+name+`" value="" class="valid hint"`) {
+				t.Errorf("%s: single %s is not highlighted empty", tt.name, cellName(c.Row, c.Col))
+			}
+		}
+	}
+}
+
+// boardRe matches a cell of the sudoku page with its row, column, and value
+var boardRe = regexp.MustCompile(`name="(\d)_(\d)_\d(?:_ro)?" value="(\d?)"`)
+
+// pageBoard reads the board shown on the sudoku page, 0 for a blank
+func pageBoard(body string) string {
+	var g Grid
+	for _, m := range boardRe.FindAllStringSubmatch(body, -1) {
+		if m[3] != "" {
+			g[m[1][0]-'0'][m[2][0]-'0'] = int(m[3][0] - '0')
+		}
+	}
+	return g.String()
+}
+
+func TestForced(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	stalls := mustGrid(t, testStalls)
+	conflict := puzzle
+	conflict[0][2] = 5
+	tests := []struct {
+		name          string
+		givens, board Grid
+		filled        int
+		status        string
+		final         string // board shown after the fill, unchecked if empty
+	}{
+		{"puzzle", puzzle, puzzle, 51, "Status: Filled 51 forced cells, logic solves the puzzle", testSolution},
+		{"solved", puzzle, mustGrid(t, testSolution), 0, "Status: Filled 0 forced cells, logic solves the puzzle", testSolution},
+		// only R8C3 = 1 is forced before the puzzle needs harder reasoning
+		{"stalls", stalls, stalls, 1, "Status: Filled 1 forced cells, 57 cells need harder reasoning",
+			"100007090030020008009600500005300900010080002600004000300000010041000007007000300"},
+		{"conflict", puzzle, conflict, 0, "Status: Cannot fill forced cells, fix the invalid entries first", ""},
+	}
+	for _, tt := range tests {
+		rec := postForm("forced", boardForm(tt.givens, tt.board))
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `value="`+tt.status+`"`) {
+			t.Errorf("%s: status code %d, page does not show %q", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if n := strings.Count(body, ` solved"`); n != tt.filled {
+			t.Errorf("%s: %d cells marked filled, want %d", tt.name, n, tt.filled)
+		}
+		if got := pageBoard(body); tt.final != "" && got != tt.final {
+			t.Errorf("%s: board %s, want %s", tt.name, got, tt.final)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+/*
+ Import of puzzles from text.
+ A puzzle is either a single line of 81 characters or nine lines of nine,
+ as in the .sdk layout and the bundled grid files.  Digits 1-9 are givens,
+ 0 and . are empty cells, and lines starting with # or [ are comments or
+ section headers.  Tools can also post JSON listing each filled cell with
+ a readonly flag, so a game in progress imports with its givens locked.
+ A grid read by OCR marks each digit it is unsure of with a following ?,
+ as in 53?0070000, and those digits are checked against the solution.
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	patternImportJSON = "/sudoku-import-json" // puzzle with per cell readonly flags
+	patternImportOCR  = "/api/import-ocr"     // check uncertain digits of a scanned grid
+
+	importTimeout = 5 * time.Second // limit on fetching a remote puzzle
+	maxImportSize = 4096            // largest remote puzzle in bytes
+)
+
+var errImportScheme = errors.New("puzzle URL must use http or https")
+
+// importClient fetches remote puzzles, refusing redirects to other schemes
+var importClient = &http.Client{
+	Timeout: importTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return errImportScheme
+		}
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return nil
+	},
+}
+
+// ReadPuzzle reads a puzzle file.  Blank lines and lines starting with
+// # or [ are skipped.  The data is either one row of 81 characters or
+// nine rows of nine, and the digits of a row may be separated by spaces.
+func ReadPuzzle(r io.Reader) (Grid, error) {
+	var data []string
+	input := bufio.NewScanner(r)
+	for input.Scan() {
+		line := strings.Join(strings.Fields(input.Text()), "")
+		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		data = append(data, line)
+	}
+	if err := input.Err(); err != nil {
+		return Grid{}, err
+	}
+	if len(data) != 1 && len(data) != rows {
+		return Grid{}, fmt.Errorf("found %d data rows, want %d: %w", len(data), rows, errGridFormat)
+	}
+	for _, line := range data {
+		if len(data) == rows && len(line) != cols {
+			return Grid{}, fmt.Errorf("row %q does not have %d digits: %w", line, cols, errGridFormat)
+		}
+	}
+	return ParseGrid(strings.Join(data, ""))
+}
+
+// ParsePuzzleText reads a puzzle in the 81 character or .sdk layout
+func ParsePuzzleText(text string) (Grid, error) {
+	return ReadPuzzle(strings.NewReader(text))
+}
+
+// fetchPuzzle downloads and parses a puzzle from an http or https URL
+func fetchPuzzle(rawURL string) (Grid, error) {
+	var g Grid
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return g, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return g, errImportScheme
+	}
+	resp, err := importClient.Get(u.String())
+	if err != nil {
+		return g, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return g, fmt.Errorf("fetch %s: %s", u.Host, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return g, err
+	}
+	if len(body) > maxImportSize {
+		return g, fmt.Errorf("puzzle is larger than %d bytes", maxImportSize)
+	}
+	return ParsePuzzleText(string(body))
+}
+
+// newPuzzle fills a SudokuT with the givens as readonly cells and the
+// other digits of board as player entries
+func newPuzzle(givens, board Grid) SudokuT {
+	var sudoku SudokuT
+	sudoku.Grid = make(map[string]Cell)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+			// Set readonly cell by appending "_ro"
+			if givens[row][col] > 0 {
+				val := strconv.Itoa(givens[row][col])
+				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
+			} else if board[row][col] > 0 {
+				val := strconv.Itoa(board[row][col])
+				sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
+			} else {
+				sudoku.Grid[name] = Cell{Name: name, Value: "", Invalid: "valid", Readonly: ""}
+			}
+		}
+	}
+	sudoku.Status.Message = "Status: Valid Puzzle"
+	sudoku.Status.State = "validstatus"
+	return sudoku
+}
+
+// importURLSubmit processes the Sudoku form submission for the importurl option
+func importURLSubmit(w http.ResponseWriter, r *http.Request) {
+	var sudoku SudokuT
+	g, err := fetchPuzzle(r.FormValue("url"))
+	switch {
+	case err != nil:
+		// Keep the current board and report the failure
+		givens, board, berr := readBoard(r)
+		if berr != nil {
+			http.Error(w, "Bad form submission: "+berr.Error(), http.StatusBadRequest)
+			return
+		}
+		sudoku = newPuzzle(givens, board)
+		sudoku.Status.Message = "Status: Import failed, " + err.Error()
+		sudoku.Status.State = "invalidstatus"
+		if errors.Is(err, errImportScheme) || errors.Is(err, errGridFormat) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	case !g.GivensConsistent():
+		sudoku = newPuzzle(g, g)
+		sudoku.Status.Message = "Status: Imported puzzle breaks the rules"
+		sudoku.Status.State = "invalidstatus"
+	default:
+		sudoku = newPuzzle(g, g)
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// CellJSON is one filled cell of a JSON puzzle import.  Readonly cells are
+// givens, the others are entries the player can still change.
+type CellJSON struct {
+	Row      int  `json:"row"`
+	Col      int  `json:"col"`
+	Value    int  `json:"value"`
+	Readonly bool `json:"readonly"`
+}
+
+// ParsePuzzleJSON reads {"cells": [...]} listing the filled cells of a
+// puzzle and checks that they obey the Sudoku rules
+func ParsePuzzleJSON(data []byte) (SudokuT, error) {
+	var req struct {
+		Cells []CellJSON `json:"cells"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return SudokuT{}, err
+	}
+	var givens, board Grid
+	for _, c := range req.Cells {
+		if !inBounds(c.Row, c.Col) {
+			return SudokuT{}, fmt.Errorf("cell %d,%d: %v", c.Row, c.Col, errOob)
+		}
+		if !validDigit(c.Value) {
+			return SudokuT{}, fmt.Errorf("cell %s: %v", cellName(c.Row, c.Col), errInvalDig)
+		}
+		if board[c.Row][c.Col] != 0 {
+			return SudokuT{}, fmt.Errorf("cell %s is listed twice", cellName(c.Row, c.Col))
+		}
+		board[c.Row][c.Col] = c.Value
+		if c.Readonly {
+			givens[c.Row][c.Col] = c.Value
+		}
+	}
+	if !board.GivensConsistent() {
+		return SudokuT{}, errRules
+	}
+	return newPuzzle(givens, board), nil
+}
+
+// handleImportJSON renders a puzzle posted as JSON with per cell readonly flags
+func handleImportJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method must be POST", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sudoku, err := ParsePuzzleJSON(data)
+	if err != nil {
+		http.Error(w, "Bad puzzle: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// ParseOCR reads a puzzle in the text layouts of ReadPuzzle where a digit
+// followed by ? is uncertain.  It returns the certain digits and, apart,
+// the uncertain ones.
+func ParseOCR(text string) (certain, uncertain Grid, err error) {
+	var cells []byte
+	var unsure []bool
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), "")
+		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		for i := 0; i < len(line); i++ {
+			if line[i] == '?' {
+				if len(cells) == 0 || cells[len(cells)-1] < '1' || cells[len(cells)-1] > '9' || unsure[len(unsure)-1] {
+					return certain, uncertain, fmt.Errorf("? must follow a digit 1-9: %w", errGridFormat)
+				}
+				unsure[len(unsure)-1] = true
+				continue
+			}
+			cells = append(cells, line[i])
+			unsure = append(unsure, false)
+		}
+	}
+	g, err := ParseGrid(string(cells))
+	if err != nil {
+		return certain, uncertain, err
+	}
+	for i, u := range unsure {
+		row, col := i/cols, i%cols
+		if u {
+			uncertain[row][col] = g[row][col]
+		} else {
+			certain[row][col] = g[row][col]
+		}
+	}
+	return certain, uncertain, nil
+}
+
+// OCRConflict is an uncertain digit that disagrees with the solution
+type OCRConflict struct {
+	Clue
+	Solution int `json:"solution"`
+}
+
+// handleImportOCR solves a scanned puzzle with its uncertain digits left
+// empty and reports the uncertain digits that disagree with the solution.
+// When the certain digits allow more than one solution, no solution can
+// settle the uncertain digits, so only unique false is reported.
+func handleImportOCR(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"`
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	certain, uncertain, err := ParseOCR(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sols := certain.Solutions(2)
+	if len(sols) == 0 {
+		writeJSONError(w, http.StatusUnprocessableEntity, "certain digits have no solution")
+		return
+	}
+	var resp struct {
+		Solution  string        `json:"solution,omitempty"`
+		Unique    bool          `json:"unique"`
+		Conflicts []OCRConflict `json:"conflicts"` // null when not unique
+	}
+	if len(sols) > 1 {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	resp.Solution = sols[0].String()
+	resp.Unique = true
+	resp.Conflicts = []OCRConflict{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if d := uncertain[row][col]; d != 0 && d != sols[0][row][col] {
+				resp.Conflicts = append(resp.Conflicts, OCRConflict{Clue{Row: row, Col: col, Value: d}, sols[0][row][col]})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
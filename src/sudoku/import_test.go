@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePuzzleJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+		givens  int
+		entries int
+	}{
+		{"mixed", `{"cells":[{"row":0,"col":0,"value":5,"readonly":true},{"row":0,"col":1,"value":3}]}`, false, 1, 1},
+		{"empty", `{"cells":[]}`, false, 0, 0},
+		{"not json", `{"cells":`, true, 0, 0},
+		{"out of bounds", `{"cells":[{"row":9,"col":0,"value":5}]}`, true, 0, 0},
+		{"bad digit", `{"cells":[{"row":0,"col":0,"value":10}]}`, true, 0, 0},
+		{"listed twice", `{"cells":[{"row":0,"col":0,"value":5},{"row":0,"col":0,"value":6}]}`, true, 0, 0},
+		{"breaks rules", `{"cells":[{"row":0,"col":0,"value":5},{"row":0,"col":8,"value":5,"readonly":true}]}`, true, 0, 0},
+	}
+	for _, tt := range tests {
+		s, err := ParsePuzzleJSON([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		givens, entries := 0, 0
+		for _, cell := range s.Grid {
+			switch {
+			case cell.Readonly == "readonly":
+				givens++
+			case cell.Value != "":
+				entries++
+			}
+		}
+		if givens != tt.givens || entries != tt.entries {
+			t.Errorf("%s: %d givens and %d entries, want %d and %d", tt.name, givens, entries, tt.givens, tt.entries)
+		}
+	}
+}
+
+func TestHandleImportJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"mixed", http.MethodPost, `{"cells":[{"row":0,"col":0,"value":5,"readonly":true},{"row":0,"col":1,"value":3}]}`, http.StatusOK},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"bad puzzle", http.MethodPost, `{"cells":[{"row":0,"col":0,"value":0}]}`, http.StatusBadRequest},
+		{"too large", http.MethodPost, `{"cells":[` + strings.Repeat(" ", maxBodySize) + `]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleImportJSON(rec, httptest.NewRequest(tt.method, patternImportJSON, strings.NewReader(tt.body)))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+		}
+		if tt.name == "too large" && !strings.Contains(rec.Body.String(), "too large") {
+			t.Errorf("%s: body %q does not report the size", tt.name, rec.Body.String())
+		}
+	}
+}
+
+// puzzleServer serves the test puzzle files used by the URL import tests
+func puzzleServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/puzzle.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPuzzle + "\n"))
+	})
+	mux.HandleFunc("/broken.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("55" + testPuzzle[2:]))
+	})
+	mux.HandleFunc("/garbage.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a puzzle"))
+	})
+	mux.HandleFunc("/large.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("#\n", maxImportSize)))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetchPuzzle(t *testing.T) {
+	srv := puzzleServer()
+	defer srv.Close()
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{srv.URL + "/puzzle.txt", false},
+		{srv.URL + "/missing.txt", true},
+		{srv.URL + "/garbage.txt", true},
+		{srv.URL + "/large.txt", true},
+		{"ftp://example.com/puzzle.txt", true},
+		{"file:///etc/passwd", true},
+	}
+	for _, tt := range tests {
+		g, err := fetchPuzzle(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fetchPuzzle(%s) error %v, want error %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && g.String() != testPuzzle {
+			t.Errorf("fetchPuzzle(%s) = %s, want %s", tt.url, g, testPuzzle)
+		}
+	}
+}
+
+func TestImportURLSubmit(t *testing.T) {
+	srv := puzzleServer()
+	defer srv.Close()
+	tests := []struct {
+		url    string
+		code   int
+		status string
+	}{
+		{srv.URL + "/puzzle.txt", http.StatusOK, "Status: Valid Puzzle"},
+		{srv.URL + "/broken.txt", http.StatusOK, "Status: Imported puzzle breaks the rules"},
+		{srv.URL + "/garbage.txt", http.StatusBadRequest, "Status: Import failed"},
+		{"ftp://example.com/puzzle.txt", http.StatusBadRequest, "Status: Import failed, puzzle URL must use http or https"},
+		{srv.URL + "/missing.txt", http.StatusBadGateway, "Status: Import failed"},
+	}
+	for _, tt := range tests {
+		form := url.Values{"url": {tt.url}}
+		rec := postForm("importurl", form)
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), `value="`+tt.status) {
+			t.Errorf("%s: status code %d, want %d showing %q", tt.url, rec.Code, tt.code, tt.status)
+		}
+	}
+}
+
+func TestReadPuzzle(t *testing.T) {
+	rowsOf := func(s string) string {
+		var lines []string
+		for i := 0; i < rows; i++ {
+			lines = append(lines, strings.Join(strings.Split(s[i*cols:(i+1)*cols], ""), " "))
+		}
+		return strings.Join(lines, "\n")
+	}
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"one line", testPuzzle, false},
+		{"nine rows", rowsOf(testPuzzle), false},
+		{"comments and blank lines", "# daily puzzle\n\n[Puzzle]\n" + rowsOf(testPuzzle) + "\n\n", false},
+		{"crlf", strings.Replace(rowsOf(testPuzzle), "\n", "\r\n", -1), false},
+		{"short row", strings.Replace(rowsOf(testPuzzle), "5 3 0", "5 3", 1), true},
+		{"eight rows", rowsOf(testPuzzle)[18:], true},
+		{"only comments", "# nothing\n", true},
+	}
+	for _, tt := range tests {
+		g, err := ReadPuzzle(strings.NewReader(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && g.String() != testPuzzle {
+			t.Errorf("%s: ReadPuzzle = %s, want %s", tt.name, g, testPuzzle)
+		}
+	}
+}
+
+func TestParseOCR(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		unsure  [][2]int
+		wantErr bool
+	}{
+		{"certain", testPuzzle, nil, false},
+		{"one unsure", "5?3" + testPuzzle[2:], [][2]int{{0, 0}}, false},
+		{"two unsure", "5?3?" + testPuzzle[2:80] + "9?", [][2]int{{0, 0}, {0, 1}, {8, 8}}, false},
+		{"rows", "5?30 070 000\n" + strings.Repeat("000000000\n", 8), [][2]int{{0, 0}}, false},
+		{"leading ?", "?" + testPuzzle, nil, true},
+		{"after empty", testPuzzle[:2] + "0?" + testPuzzle[3:], nil, true},
+		{"doubled", "5??" + testPuzzle[1:], nil, true},
+		{"short", "5?3", nil, true},
+	}
+	for _, tt := range tests {
+		certain, uncertain, err := ParseOCR(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if uncertain.Clues() != len(tt.unsure) {
+			t.Errorf("%s: %d uncertain digits, want %d", tt.name, uncertain.Clues(), len(tt.unsure))
+		}
+		for _, rc := range tt.unsure {
+			if uncertain[rc[0]][rc[1]] == 0 || certain[rc[0]][rc[1]] != 0 {
+				t.Errorf("%s: %s is not only uncertain", tt.name, cellName(rc[0], rc[1]))
+			}
+		}
+	}
+}
+
+func TestHandleImportOCR(t *testing.T) {
+	var allUncertain string
+	for _, c := range testPuzzle {
+		allUncertain += string(c)
+		if c != '0' {
+			allUncertain += "?"
+		}
+	}
+	tests := []struct {
+		name      string
+		puzzle    string
+		code      int
+		solution  string
+		unique    bool
+		conflicts []OCRConflict
+	}{
+		{"right guess", "5?" + testPuzzle[1:], http.StatusOK, testSolution, true, []OCRConflict{}},
+		{"wrong guess", "6?" + testPuzzle[1:], http.StatusOK, testSolution, true, []OCRConflict{{Clue{Row: 0, Col: 0, Value: 6}, 5}}},
+		// with every digit uncertain the certain digits allow many solutions
+		{"ambiguous", allUncertain, http.StatusOK, "", false, nil},
+		{"no solution", "55" + testPuzzle[2:], http.StatusUnprocessableEntity, "", false, nil},
+		{"bad ?", "?" + testPuzzle, http.StatusBadRequest, "", false, nil},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Solution  string        `json:"solution"`
+			Unique    bool          `json:"unique"`
+			Conflicts []OCRConflict `json:"conflicts"`
+		}
+		code := callAPI(t, handleImportOCR, http.MethodPost, patternImportOCR, `{"puzzle":"`+tt.puzzle+`"}`, &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code != http.StatusOK {
+			continue
+		}
+		if resp.Solution != tt.solution || resp.Unique != tt.unique || !reflect.DeepEqual(resp.Conflicts, tt.conflicts) {
+			t.Errorf("%s: response %+v, want solution %q, unique %v, conflicts %v", tt.name, resp, tt.solution, tt.unique, tt.conflicts)
+		}
+	}
+}
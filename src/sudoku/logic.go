@@ -0,0 +1,768 @@
+/*
+ Logical solver for the Sudoku grid.
+ Instead of guessing, the logical solver applies the techniques a person
+ would use, in order of difficulty, and records every deduction as a Step.
+ A step either places a digit in a cell or eliminates candidates from
+ cells, so a learner can follow the full chain of reasoning.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"math/rand"
+	"strings"
+)
+
+// Elimination removes a candidate digit from a cell
+type Elimination struct {
+	Row   int `json:"row"`
+	Col   int `json:"col"`
+	Value int `json:"value"`
+}
+
+// Step is one deduction of the logical solver, either the placement
+// of a digit or the elimination of candidates
+type Step struct {
+	Technique    string        `json:"technique"`
+	Placement    *Clue         `json:"placement,omitempty"`
+	Eliminations []Elimination `json:"eliminations,omitempty"`
+	Text         string        `json:"text"`
+}
+
+// logic holds the grid and the remaining candidates of every empty cell
+type logic struct {
+	g       Grid
+	cand    [rows][cols]uint16 // bit d set when digit d is still possible
+	hardest int                // highest difficulty of the techniques applied
+	techs   []technique        // techniques allowed, easiest first
+}
+
+// Difficulty levels of puzzles and techniques
+const (
+	easy = iota
+	medium
+	hard
+	expert
+)
+
+var difficultyNames = []string{"easy", "medium", "hard", "expert"}
+
+// technique is one deduction rule of the logical solver
+type technique struct {
+	name  string
+	level int // difficulty of the technique
+	apply func(l *logic) (Step, bool)
+}
+
+// techniques in the order the logical solver tries them, easiest first
+var techniques = []technique{
+	{"Naked single", easy, (*logic).nakedSingle},
+	{"Hidden single", medium, (*logic).hiddenSingle},
+	{"Pointing pair", hard, (*logic).pointingPair},
+	{"Box/line reduction", hard, (*logic).boxLineReduction},
+	{"Hidden pair", expert, (*logic).hiddenPair},
+	{"X-Wing", expert, func(l *logic) (Step, bool) { return l.fish(2) }},
+	{"Swordfish", expert, func(l *logic) (Step, bool) { return l.fish(3) }},
+}
+
+// cellName formats a location in the usual R1C1 notation, rows and columns from 1
+func cellName(row, col int) string {
+	return fmt.Sprintf("R%dC%d", row+1, col+1)
+}
+
+// digitBit returns the candidate bit of a digit, none for a value outside
+// 1-9 so a malformed board cannot shift by a negative amount
+func digitBit(d int) uint16 {
+	if !validDigit(d) {
+		return 0
+	}
+	return 1 << d
+}
+
+// Candidates returns the digits that can be placed in the cell without
+// breaking the rules, bit d set for digit d.  Filled cells have none.
+func (g Grid) Candidates(row, col int) uint16 {
+	if g[row][col] != 0 {
+		return 0
+	}
+	var used uint16
+	for i := 0; i < 9; i++ {
+		used |= digitBit(g[row][i]) | digitBit(g[i][col])
+	}
+	r0, c0 := (row/3)*3, (col/3)*3
+	for r := r0; r < r0+3; r++ {
+		for c := c0; c < c0+3; c++ {
+			used |= digitBit(g[r][c])
+		}
+	}
+	return allDigits &^ used
+}
+
+// CandidateSnapshot returns the candidates of every cell in one pass over
+// the grid, bit d set for digit d and no candidates for filled cells.
+// Values outside 1-9 take no candidates from their units.
+func (g Grid) CandidateSnapshot() [rows][cols]uint16 {
+	var rowUsed, colUsed, boxUsed [9]uint16
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			bit := digitBit(g[row][col])
+			rowUsed[row] |= bit
+			colUsed[col] |= bit
+			boxUsed[(row/3)*3+col/3] |= bit
+		}
+	}
+	var snap [rows][cols]uint16
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] == 0 {
+				snap[row][col] = allDigits &^ (rowUsed[row] | colUsed[col] | boxUsed[(row/3)*3+col/3])
+			}
+		}
+	}
+	return snap
+}
+
+// newLogic computes the candidates of every empty cell of the grid
+func newLogic(g Grid) *logic {
+	return &logic{g: g, cand: g.CandidateSnapshot(), techs: techniques}
+}
+
+// TechniqueNames returns the names of the implemented techniques, easiest first
+func TechniqueNames() []string {
+	names := make([]string, len(techniques))
+	for i, tq := range techniques {
+		names[i] = tq.name
+	}
+	return names
+}
+
+// selectTechniques returns the techniques with the given names, ignoring
+// case, in the order the logical solver tries them
+func selectTechniques(names []string) ([]technique, error) {
+	want := make(map[string]bool)
+	for _, name := range names {
+		want[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	var techs []technique
+	for _, tq := range techniques {
+		if want[strings.ToLower(tq.name)] {
+			techs = append(techs, tq)
+			delete(want, strings.ToLower(tq.name))
+		}
+	}
+	for name := range want {
+		return nil, fmt.Errorf("unknown technique %q, want one of %s", name, strings.Join(TechniqueNames(), ", "))
+	}
+	return techs, nil
+}
+
+// place sets the digit and removes it from the candidates of the cell's peers
+func (l *logic) place(row, col, d int) {
+	l.g[row][col] = d
+	l.cand[row][col] = 0
+	bit := uint16(1) << d
+	for i := 0; i < 9; i++ {
+		l.cand[row][i] &^= bit
+		l.cand[i][col] &^= bit
+	}
+	r0, c0 := (row/3)*3, (col/3)*3
+	for r := r0; r < r0+3; r++ {
+		for c := c0; c < c0+3; c++ {
+			l.cand[r][c] &^= bit
+		}
+	}
+}
+
+// eliminate removes the candidates and describes them for the step text
+func (l *logic) eliminate(elims []Elimination) string {
+	var cells []string
+	for _, e := range elims {
+		l.cand[e.Row][e.Col] &^= 1 << e.Value
+		cells = append(cells, cellName(e.Row, e.Col))
+	}
+	return fmt.Sprintf("removes %d from %s", elims[0].Value, strings.Join(cells, ", "))
+}
+
+// stuck reports whether an empty cell has no candidates left
+func (l *logic) stuck() bool {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if l.g[row][col] == 0 && l.cand[row][col] == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// peers returns the 20 cells that share a row, column, or box with the cell
+func peers(row, col int) [][2]int {
+	var ps [][2]int
+	for i := 0; i < 9; i++ {
+		if i != col {
+			ps = append(ps, [2]int{row, i})
+		}
+		if i != row {
+			ps = append(ps, [2]int{i, col})
+		}
+	}
+	r0, c0 := (row/3)*3, (col/3)*3
+	for r := r0; r < r0+3; r++ {
+		for c := c0; c < c0+3; c++ {
+			if r != row && c != col {
+				ps = append(ps, [2]int{r, c})
+			}
+		}
+	}
+	return ps
+}
+
+// unit returns the cells of row, column, or box i where kind is 0, 1, or 2
+func unit(kind, i int) [][2]int {
+	return standardUnits[kind*rows+i].Cells
+}
+
+var unitNames = [3]string{"row", "column", "box"}
+
+// nakedSingle places a digit in a cell that has only one candidate
+func (l *logic) nakedSingle() (Step, bool) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if l.g[row][col] == 0 && bits.OnesCount16(l.cand[row][col]) == 1 {
+				d := bits.TrailingZeros16(l.cand[row][col])
+				l.place(row, col, d)
+				return Step{Placement: &Clue{Row: row, Col: col, Value: d},
+					Text: fmt.Sprintf("%s = %d, the only candidate left in the cell", cellName(row, col), d)}, true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// hiddenSingle places a digit that has only one possible cell in a row, column, or box
+func (l *logic) hiddenSingle() (Step, bool) {
+	for kind := 0; kind < 3; kind++ {
+		for i := 0; i < 9; i++ {
+			cells := unit(kind, i)
+			for d := 1; d <= 9; d++ {
+				n, at := 0, 0
+				for j, rc := range cells {
+					if l.cand[rc[0]][rc[1]]&(1<<d) != 0 {
+						n++
+						at = j
+					}
+				}
+				if n == 1 {
+					row, col := cells[at][0], cells[at][1]
+					l.place(row, col, d)
+					return Step{Placement: &Clue{Row: row, Col: col, Value: d},
+						Text: fmt.Sprintf("%s = %d, the only place for %d in %s %d",
+							cellName(row, col), d, d, unitNames[kind], i+1)}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// pointingPair finds a digit whose candidates in a box lie in one row or
+// column and removes it from the rest of that row or column
+func (l *logic) pointingPair() (Step, bool) {
+	for box := 0; box < subgrids; box++ {
+		for d := 1; d <= 9; d++ {
+			bit := uint16(1) << d
+			rowSet, colSet := -1, -1
+			n := 0
+			for _, rc := range unit(2, box) {
+				if l.cand[rc[0]][rc[1]]&bit == 0 {
+					continue
+				}
+				if n == 0 {
+					rowSet, colSet = rc[0], rc[1]
+				}
+				if rc[0] != rowSet {
+					rowSet = -2
+				}
+				if rc[1] != colSet {
+					colSet = -2
+				}
+				n++
+			}
+			if n < 2 {
+				continue
+			}
+			for kind, line := range [2]int{rowSet, colSet} {
+				if line < 0 {
+					continue
+				}
+				var elims []Elimination
+				for _, rc := range unit(kind, line) {
+					if (rc[0]/3)*3+rc[1]/3 != box && l.cand[rc[0]][rc[1]]&bit != 0 {
+						elims = append(elims, Elimination{Row: rc[0], Col: rc[1], Value: d})
+					}
+				}
+				if len(elims) > 0 {
+					return Step{Eliminations: elims,
+						Text: fmt.Sprintf("%d in box %d is confined to %s %d, %s",
+							d, box+1, unitNames[kind], line+1, l.eliminate(elims))}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// boxLineReduction finds a digit whose candidates in a row or column lie
+// in one box and removes it from the rest of that box
+func (l *logic) boxLineReduction() (Step, bool) {
+	for kind := 0; kind < 2; kind++ {
+		for line := 0; line < 9; line++ {
+			for d := 1; d <= 9; d++ {
+				bit := uint16(1) << d
+				box, n := -1, 0
+				for _, rc := range unit(kind, line) {
+					if l.cand[rc[0]][rc[1]]&bit == 0 {
+						continue
+					}
+					b := (rc[0]/3)*3 + rc[1]/3
+					if n == 0 {
+						box = b
+					} else if b != box {
+						box = -1
+					}
+					n++
+				}
+				if n < 2 || box < 0 {
+					continue
+				}
+				var elims []Elimination
+				for _, rc := range unit(2, box) {
+					onLine := kind == 0 && rc[0] == line || kind == 1 && rc[1] == line
+					if !onLine && l.cand[rc[0]][rc[1]]&bit != 0 {
+						elims = append(elims, Elimination{Row: rc[0], Col: rc[1], Value: d})
+					}
+				}
+				if len(elims) > 0 {
+					return Step{Eliminations: elims,
+						Text: fmt.Sprintf("%d in %s %d is confined to box %d, %s",
+							d, unitNames[kind], line+1, box+1, l.eliminate(elims))}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// hiddenPair finds two digits that share the same two cells as their only
+// places in a row, column, or box and removes the other candidates of
+// those cells
+func (l *logic) hiddenPair() (Step, bool) {
+	for kind := 0; kind < 3; kind++ {
+		for i := 0; i < 9; i++ {
+			cells := unit(kind, i)
+			var places [10]uint16 // bit j set when digit d can go in cells[j]
+			for d := 1; d <= 9; d++ {
+				for j, rc := range cells {
+					if l.cand[rc[0]][rc[1]]&(1<<d) != 0 {
+						places[d] |= 1 << j
+					}
+				}
+			}
+			for d1 := 1; d1 <= 9; d1++ {
+				if bits.OnesCount16(places[d1]) != 2 {
+					continue
+				}
+				for d2 := d1 + 1; d2 <= 9; d2++ {
+					if places[d2] != places[d1] {
+						continue
+					}
+					pair := uint16(1)<<d1 | uint16(1)<<d2
+					var elims []Elimination
+					var names []string
+					for j, rc := range cells {
+						if places[d1]&(1<<j) == 0 {
+							continue
+						}
+						names = append(names, cellName(rc[0], rc[1]))
+						for d := 1; d <= 9; d++ {
+							if l.cand[rc[0]][rc[1]]&^pair&(1<<d) != 0 {
+								elims = append(elims, Elimination{Row: rc[0], Col: rc[1], Value: d})
+							}
+						}
+					}
+					if len(elims) == 0 {
+						continue
+					}
+					for _, e := range elims {
+						l.cand[e.Row][e.Col] &^= 1 << e.Value
+					}
+					return Step{Eliminations: elims,
+						Text: fmt.Sprintf("%d and %d in %s %d are confined to %s, removes their other candidates",
+							d1, d2, unitNames[kind], i+1, strings.Join(names, " and "))}, true
+				}
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// fish finds a digit whose candidates in size rows lie in the same size
+// columns, or the other way round, and removes it from the rest of those
+// columns.  Size 2 is the X-Wing and size 3 the Swordfish.
+func (l *logic) fish(size int) (Step, bool) {
+	for kind := 0; kind < 2; kind++ {
+		for d := 1; d <= 9; d++ {
+			bit := uint16(1) << d
+			var lines []int     // base lines with 2 to size candidates
+			var spans [9]uint16 // bit j set when the digit can go at position j of the line
+			for line := 0; line < 9; line++ {
+				for j, rc := range unit(kind, line) {
+					if l.cand[rc[0]][rc[1]]&bit != 0 {
+						spans[line] |= 1 << j
+					}
+				}
+				if n := bits.OnesCount16(spans[line]); n >= 2 && n <= size {
+					lines = append(lines, line)
+				}
+			}
+			if step, ok := l.fishFrom(kind, d, size, lines, spans, nil, 0); ok {
+				return step, true
+			}
+		}
+	}
+	return Step{}, false
+}
+
+// fishFrom extends the chosen base lines with lines from the rest of the
+// list until there are size of them, then eliminates the digit from the
+// cover lines when the base lines span exactly size of them
+func (l *logic) fishFrom(kind, d, size int, lines []int, spans [9]uint16, chosen []int, cover uint16) (Step, bool) {
+	if bits.OnesCount16(cover) > size {
+		return Step{}, false
+	}
+	if len(chosen) < size {
+		for k, line := range lines {
+			if step, ok := l.fishFrom(kind, d, size, lines[k+1:], spans, append(chosen, line), cover|spans[line]); ok {
+				return step, true
+			}
+		}
+		return Step{}, false
+	}
+	base := make(map[int]bool)
+	var baseNums, coverNums []string
+	for _, line := range chosen {
+		base[line] = true
+		baseNums = append(baseNums, fmt.Sprint(line+1))
+	}
+	var elims []Elimination
+	for j := 0; j < 9; j++ {
+		if cover&(1<<j) == 0 {
+			continue
+		}
+		coverNums = append(coverNums, fmt.Sprint(j+1))
+		for _, rc := range unit(1-kind, j) {
+			line := rc[kind]
+			if !base[line] && l.cand[rc[0]][rc[1]]&(1<<d) != 0 {
+				elims = append(elims, Elimination{Row: rc[0], Col: rc[1], Value: d})
+			}
+		}
+	}
+	if len(elims) == 0 {
+		return Step{}, false
+	}
+	return Step{Eliminations: elims,
+		Text: fmt.Sprintf("%d in %ss %s is confined to %ss %s, %s",
+			d, unitNames[kind], strings.Join(baseNums, ", "), unitNames[1-kind],
+			strings.Join(coverNums, ", "), l.eliminate(elims))}, true
+}
+
+// next applies the first technique that makes progress
+func (l *logic) next() (Step, bool) {
+	if l.stuck() {
+		return Step{}, false
+	}
+	for _, tq := range l.techs {
+		if step, ok := tq.apply(l); ok {
+			step.Technique = tq.name
+			if tq.level > l.hardest {
+				l.hardest = tq.level
+			}
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// NextPlacement returns the first digit logic can place on the grid,
+// applying any eliminations it needs on the way
+func (g Grid) NextPlacement() (Step, bool) {
+	if !g.GivensConsistent() {
+		return Step{}, false
+	}
+	l := newLogic(g)
+	for {
+		step, ok := l.next()
+		if !ok || step.Placement != nil {
+			return step, ok
+		}
+	}
+}
+
+// hardestPlacement returns a placement of the most difficult technique that
+// can place a digit on the grid as it stands.  Techniques that only
+// eliminate candidates are not counted.
+func (g Grid) hardestPlacement() (Step, bool) {
+	if !g.GivensConsistent() {
+		return Step{}, false
+	}
+	for i := len(techniques) - 1; i >= 0; i-- {
+		tq := techniques[i]
+		if step, ok := tq.apply(newLogic(g)); ok && step.Placement != nil {
+			step.Technique = tq.name
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
+// HardestImmediateMove returns the placement, as in R1C2 = 5, that needs
+// the most advanced technique applicable right now, and that technique
+func (g Grid) HardestImmediateMove() (move, technique string, ok bool) {
+	step, ok := g.hardestPlacement()
+	if !ok {
+		return "", "", false
+	}
+	p := step.Placement
+	return fmt.Sprintf("%s = %d", cellName(p.Row, p.Col), p.Value), step.Technique, true
+}
+
+// Explain solves the grid by logic alone, returning every deduction in order
+// and the grid reached when no technique applies any more
+func (g Grid) Explain() ([]Step, Grid) {
+	var steps []Step
+	if !g.GivensConsistent() {
+		return steps, g
+	}
+	l := newLogic(g)
+	for {
+		step, ok := l.next()
+		if !ok {
+			return steps, l.g
+		}
+		steps = append(steps, step)
+	}
+}
+
+// AdvancedOpportunities returns the names of the techniques beyond singles
+// that have an instance on the grid as it stands, without applying them,
+// so a player can be told what to look for without being given the move
+func (g Grid) AdvancedOpportunities() []string {
+	names := []string{}
+	if !g.GivensConsistent() {
+		return names
+	}
+	for _, tq := range techniques {
+		if tq.level <= medium {
+			continue
+		}
+		if _, ok := tq.apply(newLogic(g)); ok {
+			names = append(names, tq.name)
+		}
+	}
+	return names
+}
+
+// Confidence reports how the grid is solved: "logic only" when the
+// logical techniques finish it, "required search" when guessing is needed
+func (g Grid) Confidence() string {
+	if _, final := g.Explain(); final.Clues() == rows*cols {
+		return "logic only"
+	}
+	return "required search"
+}
+
+// parseDifficulty returns the level of a difficulty name
+func parseDifficulty(name string) (int, bool) {
+	for level, n := range difficultyNames {
+		if n == name {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// gradeLevel returns the highest difficulty of the techniques needed to
+// solve the grid.  Grids that logic cannot finish are expert.
+func (g Grid) gradeLevel() int {
+	return g.gradeLevelUsing(techniques)
+}
+
+// gradeLevelUsing grades the grid as gradeLevel does, allowing the logical
+// solver only the techniques given
+func (g Grid) gradeLevelUsing(techs []technique) int {
+	if !g.GivensConsistent() {
+		return expert
+	}
+	l := newLogic(g)
+	l.techs = techs
+	for {
+		if _, ok := l.next(); !ok {
+			break
+		}
+	}
+	if l.g.Clues() < rows*cols {
+		return expert
+	}
+	return l.hardest
+}
+
+// Grade rates the puzzle easy, medium, hard, or expert by the hardest
+// technique the logical solver needs to finish it
+func (g Grid) Grade() string {
+	return difficultyNames[g.gradeLevel()]
+}
+
+// GradeUsing rates the puzzle as Grade does when only the named
+// techniques are known, as for a tutorial that has not taught the others
+func (g Grid) GradeUsing(names []string) (string, error) {
+	techs, err := selectTechniques(names)
+	if err != nil {
+		return "", err
+	}
+	return difficultyNames[g.gradeLevelUsing(techs)], nil
+}
+
+// BranchingFactor returns the average number of candidates of the empty
+// cells once the digits of the givens are eliminated from their peers.
+// It is a quick difficulty estimate, much cheaper than Grade, and higher
+// for puzzles that leave the solver more choices.
+func (g Grid) BranchingFactor() float64 {
+	snap := g.CandidateSnapshot()
+	total, empty := 0, 0
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] == 0 {
+				total += bits.OnesCount16(snap[row][col])
+				empty++
+			}
+		}
+	}
+	if empty == 0 {
+		return 0
+	}
+	return float64(total) / float64(empty)
+}
+
+// uniqueWithin tells whether the puzzle has a unique solution and grades
+// no harder than level, the test every clue removal must pass
+func (g Grid) uniqueWithin(level int) bool {
+	return g.CountSolutions(2) == 1 && g.gradeLevel() <= level
+}
+
+// MinimizeWithin removes clues in an order chosen by rng while the puzzle
+// keeps a unique solution and grades no harder than level
+func (g Grid) MinimizeWithin(rng *rand.Rand, level int) Grid {
+	for _, i := range rng.Perm(rows * cols) {
+		row, col := i/cols, i%cols
+		d := g[row][col]
+		if d == 0 {
+			continue
+		}
+		g[row][col] = 0
+		if !g.uniqueWithin(level) {
+			g[row][col] = d
+		}
+	}
+	return g
+}
+
+// MinimizeBalanced removes clues as MinimizeWithin does but never the last
+// clue of a box, so no box of the puzzle is left empty
+func (g Grid) MinimizeBalanced(rng *rand.Rand, level int) Grid {
+	_, _, perBox := g.ClueDistribution()
+	for _, i := range rng.Perm(rows * cols) {
+		row, col := i/cols, i%cols
+		d := g[row][col]
+		box := (row/3)*3 + col/3
+		if d == 0 || perBox[box] == 1 {
+			continue
+		}
+		g[row][col] = 0
+		if !g.uniqueWithin(level) {
+			g[row][col] = d
+			continue
+		}
+		perBox[box]--
+	}
+	return g
+}
+
+// checkGenerated regrades a generated puzzle from its text form and logs a
+// warning when it is not uniquely solvable or its grade is not the
+// difficulty it is labeled with.  It starts from the output alone, so it
+// also catches a generator whose own filter drifts from the grader.
+func checkGenerated(puzzle, label string) bool {
+	g, err := ParseGrid(puzzle)
+	if err != nil {
+		log.Printf("Warning: generated puzzle %s does not parse: %v\n", puzzle, err)
+		return false
+	}
+	if n := g.CountSolutions(2); n != 1 {
+		log.Printf("Warning: generated puzzle %s has %d solutions\n", puzzle, n)
+		return false
+	}
+	if got := g.Grade(); got != label {
+		log.Printf("Warning: generated puzzle %s labeled %s grades %s\n", puzzle, label, got)
+		return false
+	}
+	return true
+}
+
+// NakedSingles returns every empty cell that has exactly one candidate,
+// with that candidate, in reading order
+func (g Grid) NakedSingles() []Clue {
+	singles := []Clue{}
+	cand := g.CandidateSnapshot()
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if bits.OnesCount16(cand[row][col]) == 1 {
+				singles = append(singles, Clue{Row: row, Col: col, Value: bits.TrailingZeros16(cand[row][col])})
+			}
+		}
+	}
+	return singles
+}
+
+// singleCells marks the empty cells that hold a naked or hidden single
+func (g Grid) singleCells() [rows][cols]bool {
+	var single [rows][cols]bool
+	cand := g.CandidateSnapshot()
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if bits.OnesCount16(cand[row][col]) == 1 {
+				single[row][col] = true
+			}
+		}
+	}
+	for kind := 0; kind < 3; kind++ {
+		for i := 0; i < 9; i++ {
+			cells := unit(kind, i)
+			for d := 1; d <= 9; d++ {
+				n, at := 0, 0
+				for j, rc := range cells {
+					if cand[rc[0]][rc[1]]&(1<<d) != 0 {
+						n++
+						at = j
+					}
+				}
+				if n == 1 {
+					single[cells[at][0]][cells[at][1]] = true
+				}
+			}
+		}
+	}
+	return single
+}
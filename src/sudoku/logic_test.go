@@ -0,0 +1,527 @@
+package main
+
+import (
+	"context"
+	"math/bits"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckGenerated(t *testing.T) {
+	tests := []struct {
+		name   string
+		puzzle string
+		label  string
+		want   bool
+	}{
+		{"matches", testPuzzle, "easy", true},
+		{"labeled harder", testPuzzle, "medium", false},
+		{"labeled unknown", testPuzzle, "trivial", false},
+		{"several solutions", Grid{}.String(), "expert", false},
+		{"does not parse", testPuzzle[:80], "easy", false},
+	}
+	for _, tt := range tests {
+		if got := checkGenerated(tt.puzzle, tt.label); got != tt.want {
+			t.Errorf("%s: checkGenerated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestGenerateLabels feeds generated puzzles back through the grader,
+// which must agree with the difficulty they are labeled with
+func TestGenerateLabels(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for level, name := range difficultyNames {
+		for i := 0; i < 3; i++ {
+			gen, ok := generate(context.Background(), rng, level)
+			if !ok {
+				t.Errorf("%s: no puzzle generated", name)
+				continue
+			}
+			if gen.Difficulty != name || !checkGenerated(gen.Puzzle, gen.Difficulty) {
+				t.Errorf("%s: puzzle %s labeled %s grades %s", name, gen.Puzzle, gen.Difficulty, mustGrid(t, gen.Puzzle).Grade())
+			}
+			if level > easy && checkGenerated(gen.Puzzle, difficultyNames[level-1]) {
+				t.Errorf("%s: puzzle %s passes the check labeled %s", name, gen.Puzzle, difficultyNames[level-1])
+			}
+		}
+	}
+}
+
+// TestTechniqueOrder checks that Explain takes a naked single before a
+// hidden single, so a puzzle needing only naked singles grades easy
+func TestTechniqueOrder(t *testing.T) {
+	g := mustGrid(t, testPuzzle)
+	if len(g.NakedSingles()) == 0 {
+		t.Fatal("test puzzle has no naked single")
+	}
+	steps, _ := g.Explain()
+	tests := []struct {
+		step      int
+		technique string
+		text      string
+	}{
+		{0, "Naked single", "R5C5 = 5, the only candidate left in the cell"},
+		{50, "Naked single", "R9C7 = 1, the only candidate left in the cell"},
+	}
+	for _, tt := range tests {
+		if s := steps[tt.step]; s.Technique != tt.technique || s.Text != tt.text {
+			t.Errorf("step %d: %s %q, want %s %q", tt.step, s.Technique, s.Text, tt.technique, tt.text)
+		}
+	}
+	if got := g.Grade(); got != "easy" {
+		t.Errorf("Grade() = %s, want easy", got)
+	}
+}
+
+// openLogic returns the logical solver on an empty grid with every
+// candidate open except digit d outside keep in box 0
+func openLogic(d int, keep ...[2]int) *logic {
+	l := newLogic(Grid{})
+	for _, rc := range unit(2, 0) {
+		l.cand[rc[0]][rc[1]] &^= 1 << d
+	}
+	for _, rc := range keep {
+		l.cand[rc[0]][rc[1]] |= 1 << d
+	}
+	return l
+}
+
+func TestPointingPair(t *testing.T) {
+	tests := []struct {
+		name  string
+		l     *logic
+		ok    bool
+		elims []Elimination
+		text  string
+	}{
+		{"open grid", newLogic(Grid{}), false, nil, ""},
+		{"row", openLogic(1, [2]int{0, 0}, [2]int{0, 1}), true,
+			[]Elimination{{0, 3, 1}, {0, 4, 1}, {0, 5, 1}, {0, 6, 1}, {0, 7, 1}, {0, 8, 1}},
+			"1 in box 1 is confined to row 1, removes 1 from R1C4, R1C5, R1C6, R1C7, R1C8, R1C9"},
+		{"column", openLogic(2, [2]int{0, 0}, [2]int{1, 0}, [2]int{2, 0}), true,
+			[]Elimination{{3, 0, 2}, {4, 0, 2}, {5, 0, 2}, {6, 0, 2}, {7, 0, 2}, {8, 0, 2}},
+			"2 in box 1 is confined to column 1, removes 2 from R4C1, R5C1, R6C1, R7C1, R8C1, R9C1"},
+		{"single cell", openLogic(3, [2]int{0, 0}), false, nil, ""},
+	}
+	for _, tt := range tests {
+		step, ok := tt.l.pointingPair()
+		if ok != tt.ok {
+			t.Errorf("%s: found %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(step.Eliminations, tt.elims) || step.Text != tt.text || step.Placement != nil {
+			t.Errorf("%s: step %+v\nwant eliminations %v, text %q", tt.name, step, tt.elims, tt.text)
+		}
+		for _, e := range tt.elims {
+			if tt.l.cand[e.Row][e.Col]&(1<<e.Value) != 0 {
+				t.Errorf("%s: %d still a candidate of %s", tt.name, e.Value, cellName(e.Row, e.Col))
+			}
+		}
+	}
+}
+
+// lineLogic returns the logical solver on an empty grid with every
+// candidate open except digit d outside keep in row or column line
+func lineLogic(d, kind, line int, keep ...[2]int) *logic {
+	l := newLogic(Grid{})
+	for _, rc := range unit(kind, line) {
+		l.cand[rc[0]][rc[1]] &^= 1 << d
+	}
+	for _, rc := range keep {
+		l.cand[rc[0]][rc[1]] |= 1 << d
+	}
+	return l
+}
+
+func TestBoxLineReduction(t *testing.T) {
+	tests := []struct {
+		name  string
+		l     *logic
+		ok    bool
+		elims []Elimination
+		text  string
+	}{
+		{"open grid", newLogic(Grid{}), false, nil, ""},
+		{"row", lineLogic(1, 0, 0, [2]int{0, 0}, [2]int{0, 2}), true,
+			[]Elimination{{1, 0, 1}, {1, 1, 1}, {1, 2, 1}, {2, 0, 1}, {2, 1, 1}, {2, 2, 1}},
+			"1 in row 1 is confined to box 1, removes 1 from R2C1, R2C2, R2C3, R3C1, R3C2, R3C3"},
+		{"column", lineLogic(5, 1, 4, [2]int{3, 4}, [2]int{4, 4}, [2]int{5, 4}), true,
+			[]Elimination{{3, 3, 5}, {3, 5, 5}, {4, 3, 5}, {4, 5, 5}, {5, 3, 5}, {5, 5, 5}},
+			"5 in column 5 is confined to box 5, removes 5 from R4C4, R4C6, R5C4, R5C6, R6C4, R6C6"},
+		{"two boxes", lineLogic(2, 0, 0, [2]int{0, 0}, [2]int{0, 3}), false, nil, ""},
+		{"single cell", lineLogic(3, 0, 0, [2]int{0, 0}), false, nil, ""},
+	}
+	for _, tt := range tests {
+		step, ok := tt.l.boxLineReduction()
+		if ok != tt.ok {
+			t.Errorf("%s: found %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(step.Eliminations, tt.elims) || step.Text != tt.text || step.Placement != nil {
+			t.Errorf("%s: step %+v\nwant eliminations %v, text %q", tt.name, step, tt.elims, tt.text)
+		}
+		for _, e := range tt.elims {
+			if tt.l.cand[e.Row][e.Col]&(1<<e.Value) != 0 {
+				t.Errorf("%s: %d still a candidate of %s", tt.name, e.Value, cellName(e.Row, e.Col))
+			}
+		}
+	}
+}
+
+func TestCandidateSnapshot(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+	}{
+		{"empty", Grid{}},
+		{"puzzle", mustGrid(t, testPuzzle)},
+		{"stalls", mustGrid(t, testStalls)},
+		{"solved", mustGrid(t, testSolution)},
+	}
+	for _, tt := range tests {
+		snap := tt.g.CandidateSnapshot()
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				if want := tt.g.Candidates(row, col); snap[row][col] != want {
+					t.Errorf("%s: %s candidates %b, want %b", tt.name, cellName(row, col), snap[row][col], want)
+				}
+			}
+		}
+	}
+
+	// spot checks of the test puzzle: R1C3 can be 1, 2, or 4
+	if got := mustGrid(t, testPuzzle).CandidateSnapshot()[0][2]; got != 1<<1|1<<2|1<<4 {
+		t.Errorf("R1C3 candidates %b, want %b", got, 1<<1|1<<2|1<<4)
+	}
+}
+
+func TestBranchingFactor(t *testing.T) {
+	sol := mustGrid(t, testSolution)
+	hole := sol
+	hole[4][4] = 0
+	// rows 0 and 3 swap 6 and 7 in columns 3 and 4, leaving both candidates
+	rect := sol
+	rect[0][3], rect[0][4], rect[3][3], rect[3][4] = 0, 0, 0, 0
+	tests := []struct {
+		name string
+		g    Grid
+		min  float64
+		max  float64
+	}{
+		{"empty", Grid{}, 9, 9},
+		{"solution", sol, 0, 0},
+		{"one hole", hole, 1, 1},
+		{"unavoidable rectangle", rect, 2, 2},
+		{"puzzle", mustGrid(t, testPuzzle), 1, 9},
+	}
+	for _, tt := range tests {
+		if bf := tt.g.BranchingFactor(); bf < tt.min || bf > tt.max {
+			t.Errorf("%s: BranchingFactor = %v, want %v-%v", tt.name, bf, tt.min, tt.max)
+		}
+	}
+}
+
+func TestNakedSingles(t *testing.T) {
+	sol := mustGrid(t, testSolution)
+	holes := sol
+	holes[4][4], holes[8][0] = 0, 0
+	rect := sol
+	rect[0][3], rect[0][4], rect[3][3], rect[3][4] = 0, 0, 0, 0
+	tests := []struct {
+		name string
+		g    Grid
+		want []Clue
+	}{
+		{"empty", Grid{}, []Clue{}},
+		{"solution", sol, []Clue{}},
+		{"two holes", holes, []Clue{{4, 4, 5}, {8, 0, 3}}},
+		{"unavoidable rectangle", rect, []Clue{}},
+	}
+	for _, tt := range tests {
+		if got := tt.g.NakedSingles(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NakedSingles = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// every empty cell of the puzzle with one candidate, in reading order
+	g := mustGrid(t, testPuzzle)
+	want := []Clue{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if c := g.Candidates(row, col); g[row][col] == 0 && bits.OnesCount16(c) == 1 {
+				want = append(want, Clue{row, col, bits.TrailingZeros16(c)})
+			}
+		}
+	}
+	if got := g.NakedSingles(); len(want) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("puzzle: NakedSingles = %v, want %v", got, want)
+	}
+}
+
+func TestHardestImmediateMove(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	hole := mustGrid(t, testSolution)
+	hole[4][4] = 0
+	conflict := puzzle
+	conflict[0][2] = 5
+	tests := []struct {
+		name      string
+		g         Grid
+		moves     []string
+		technique string
+		ok        bool
+	}{
+		{"one hole", hole, []string{"R5C5 = 5"}, "Hidden single", true},
+		{"puzzle", puzzle, []string{"R3C7 = 5"}, "Hidden single", true},
+		{"solved", mustGrid(t, testSolution), nil, "", false},
+		{"conflict", conflict, nil, "", false},
+	}
+	for _, tt := range tests {
+		move, technique, ok := tt.g.HardestImmediateMove()
+		if ok != tt.ok || technique != tt.technique {
+			t.Errorf("%s: HardestImmediateMove = %q, %q, %v, want %q, %v", tt.name, move, technique, ok, tt.technique, tt.ok)
+			continue
+		}
+		found := !ok
+		for _, m := range tt.moves {
+			found = found || m == move
+		}
+		if !found {
+			t.Errorf("%s: move %q, want one of %v", tt.name, move, tt.moves)
+		}
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+		want string
+		page bool // the trial solver of the solve page always solves it
+	}{
+		{"puzzle", mustGrid(t, testPuzzle), "logic only", true},
+		{"solution", mustGrid(t, testSolution), "logic only", false},
+		{"stalls", mustGrid(t, testStalls), "required search", false},
+		{"empty", Grid{}, "required search", true},
+	}
+	for _, tt := range tests {
+		if got := tt.g.Confidence(); got != tt.want {
+			t.Errorf("%s: Confidence = %q, want %q", tt.name, got, tt.want)
+		}
+		if !tt.page {
+			continue
+		}
+		// the solve page reports it with the timing
+		body := postForm("solve", boardForm(tt.g, tt.g)).Body.String()
+		if status := "Status: Solved (" + tt.want + ") in "; !strings.Contains(body, status) {
+			t.Errorf("%s: solve page does not show %q", tt.name, status)
+		}
+	}
+}
+
+func TestGradeUsing(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name    string
+		names   []string
+		want    string
+		wantErr bool
+	}{
+		{"all", TechniqueNames(), "easy", false},
+		{"hidden single", []string{"Hidden single"}, "medium", false},
+		{"naked single", []string{"Naked single"}, "easy", false},
+		{"case and spaces", []string{" hidden SINGLE "}, "medium", false},
+		{"none", []string{}, "expert", false},
+		{"unknown", []string{"Hidden single", "Guess"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := puzzle.GradeUsing(tt.names)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: GradeUsing(%q) = %q, %v, want %q, error %v", tt.name, tt.names, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// rowLogic returns the logical solver on an empty grid with every
+// candidate open except digit d outside keep in the rows of keep
+func rowLogic(d int, keep ...[2]int) *logic {
+	l := newLogic(Grid{})
+	for _, rc := range keep {
+		for _, cell := range unit(0, rc[0]) {
+			l.cand[cell[0]][cell[1]] &^= 1 << d
+		}
+	}
+	for _, rc := range keep {
+		l.cand[rc[0]][rc[1]] |= 1 << d
+	}
+	return l
+}
+
+// coverElims returns the eliminations of digit d from the columns in
+// cover outside the base rows, in the order fish makes them
+func coverElims(d int, base, cover []int) []Elimination {
+	inBase := make(map[int]bool)
+	for _, row := range base {
+		inBase[row] = true
+	}
+	var elims []Elimination
+	for _, col := range cover {
+		for row := 0; row < rows; row++ {
+			if !inBase[row] {
+				elims = append(elims, Elimination{row, col, d})
+			}
+		}
+	}
+	return elims
+}
+
+func TestHiddenPair(t *testing.T) {
+	// 1 and 2 can go only in R1C1 and R1C2 of row 1
+	pair := rowLogic(1, [2]int{0, 0}, [2]int{0, 1})
+	for col := 2; col < cols; col++ {
+		pair.cand[0][col] &^= 1 << 2
+	}
+	// the same pair with its other candidates already gone
+	bare := rowLogic(1, [2]int{0, 0}, [2]int{0, 1})
+	for col := 0; col < cols; col++ {
+		bare.cand[0][col] &^= 1 << 2
+	}
+	bare.cand[0][0], bare.cand[0][1] = 1<<1|1<<2, 1<<1|1<<2
+	var elims []Elimination
+	for col := 0; col < 2; col++ {
+		for d := 3; d <= 9; d++ {
+			elims = append(elims, Elimination{0, col, d})
+		}
+	}
+	tests := []struct {
+		name  string
+		l     *logic
+		ok    bool
+		elims []Elimination
+		text  string
+	}{
+		{"open grid", newLogic(Grid{}), false, nil, ""},
+		{"pair", pair, true, elims, "1 and 2 in row 1 are confined to R1C1 and R1C2, removes their other candidates"},
+		{"nothing to remove", bare, false, nil, ""},
+	}
+	for _, tt := range tests {
+		step, ok := tt.l.hiddenPair()
+		if ok != tt.ok {
+			t.Errorf("%s: found %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(step.Eliminations, tt.elims) || step.Text != tt.text || step.Placement != nil {
+			t.Errorf("%s: step %+v\nwant eliminations %v, text %q", tt.name, step, tt.elims, tt.text)
+		}
+		if c := tt.l.cand[0][0] | tt.l.cand[0][1]; c != 1<<1|1<<2 {
+			t.Errorf("%s: pair cells keep candidates %09b", tt.name, c>>1)
+		}
+	}
+}
+
+func TestFish(t *testing.T) {
+	tests := []struct {
+		name  string
+		l     *logic
+		size  int
+		ok    bool
+		elims []Elimination
+		text  string
+	}{
+		{"open grid", newLogic(Grid{}), 2, false, nil, ""},
+		{"x-wing", rowLogic(1, [2]int{0, 0}, [2]int{0, 5}, [2]int{4, 0}, [2]int{4, 5}), 2, true,
+			coverElims(1, []int{0, 4}, []int{0, 5}), "1 in rows 1, 5 is confined to columns 1, 6"},
+		{"x-wing spans three columns", rowLogic(1, [2]int{0, 0}, [2]int{0, 5}, [2]int{4, 0}, [2]int{4, 6}), 2, false, nil, ""},
+		{"swordfish", rowLogic(7, [2]int{0, 0}, [2]int{0, 4}, [2]int{4, 4}, [2]int{4, 8}, [2]int{8, 0}, [2]int{8, 8}), 3, true,
+			coverElims(7, []int{0, 4, 8}, []int{0, 4, 8}), "7 in rows 1, 5, 9 is confined to columns 1, 5, 9"},
+		{"swordfish is no x-wing", rowLogic(7, [2]int{0, 0}, [2]int{0, 4}, [2]int{4, 4}, [2]int{4, 8}, [2]int{8, 0}, [2]int{8, 8}), 2, false, nil, ""},
+	}
+	for _, tt := range tests {
+		step, ok := tt.l.fish(tt.size)
+		if ok != tt.ok {
+			t.Errorf("%s: found %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(step.Eliminations, tt.elims) || !strings.HasPrefix(step.Text, tt.text+", removes ") || step.Placement != nil {
+			t.Errorf("%s: step %+v\nwant eliminations %v, text %q", tt.name, step, tt.elims, tt.text)
+		}
+		for _, e := range tt.elims {
+			if tt.l.cand[e.Row][e.Col]&(1<<e.Value) != 0 {
+				t.Errorf("%s: %d still a candidate of %s", tt.name, e.Value, cellName(e.Row, e.Col))
+			}
+		}
+	}
+}
+
+func TestAdvancedOpportunities(t *testing.T) {
+	conflict := mustGrid(t, testPuzzle)
+	conflict[0][2] = 5
+	tests := []struct {
+		name string
+		g    Grid
+		want []string
+	}{
+		{"empty", Grid{}, []string{}},
+		{"solved", mustGrid(t, testSolution), []string{}},
+		{"conflict", conflict, []string{}},
+		{"puzzle", mustGrid(t, testPuzzle), []string{"Pointing pair", "Box/line reduction", "Hidden pair", "X-Wing"}},
+		{"x-wing example", mustGrid(t, "100000569492056108056109240009640801064010000218035604040500016905061402621000005"),
+			[]string{"Pointing pair", "Box/line reduction", "Hidden pair", "X-Wing", "Swordfish"}},
+	}
+	for _, tt := range tests {
+		before := tt.g
+		if got := tt.g.AdvancedOpportunities(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: AdvancedOpportunities = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.g != before {
+			t.Errorf("%s: grid changed", tt.name)
+		}
+	}
+}
+
+func TestMinimizeBalanced(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     Grid
+		seed  int64
+		level int
+	}{
+		{"solution", mustGrid(t, testSolution), 1, expert},
+		{"solution other seed", mustGrid(t, testSolution), 2, expert},
+		{"solution easy", mustGrid(t, testSolution), 3, easy},
+		{"solution medium", mustGrid(t, testSolution), 4, medium},
+		{"solution hard", mustGrid(t, testSolution), 5, hard},
+		{"puzzle", mustGrid(t, testPuzzle), 1, expert},
+	}
+	for _, tt := range tests {
+		p := tt.g.MinimizeBalanced(rand.New(rand.NewSource(tt.seed)), tt.level)
+		if n := p.CountSolutions(2); n != 1 || !tt.g.RespectsGivens(p) {
+			t.Errorf("%s: %s has %d solutions or adds clues", tt.name, p, n)
+			continue
+		}
+		_, _, perBox := p.ClueDistribution()
+		for box, n := range perBox {
+			if n == 0 {
+				t.Errorf("%s: %s leaves box %d empty", tt.name, p, box+1)
+			}
+		}
+		if p.gradeLevel() > tt.level {
+			t.Errorf("%s: grades %s, want at most %s", tt.name, p.Grade(), difficultyNames[tt.level])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ Comparison of the player's entries with the solution.
+ For a warmer/colder style of play only the number of wrong entries is
+ reported, never which ones.  Solving and comparing fills the board and
+ shows which cells the player had right, which were wrong, and which the
+ solver filled.
+*/
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Mistakes returns the number of entries of the grid, other than the givens,
+// that differ from the unique solution of the givens.  It returns false
+// when the givens have no solution or more than one.
+func (g Grid) Mistakes(givens Grid) (int, bool) {
+	sols := givens.CachedSolutions()
+	if len(sols) != 1 {
+		return 0, false
+	}
+	n := 0
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if givens[row][col] == 0 && g[row][col] != 0 && g[row][col] != sols[0][row][col] {
+				n++
+			}
+		}
+	}
+	return n, true
+}
+
+// countMistakesSubmit processes the Sudoku form submission for the countmistakes
+// option.  The board is returned unmarked with the count in the status.
+func countMistakesSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+
+	switch n, ok := board.Mistakes(givens); {
+	case !ok:
+		sudoku.Status.Message = "Status: Cannot count mistakes, the puzzle does not have a unique solution"
+		sudoku.Status.State = "invalidstatus"
+	case n == 0:
+		sudoku.Status.Message = "Status: No mistakes so far"
+	case n == 1:
+		sudoku.Status.Message = "Status: 1 entry is wrong"
+		sudoku.Status.State = "invalidstatus"
+	default:
+		sudoku.Status.Message = fmt.Sprintf("Status: %d entries are wrong", n)
+		sudoku.Status.State = "invalidstatus"
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// solveAndDiffSubmit processes the Sudoku form submission for the solveanddiff
+// option, filling the board from the unique solution of the givens and
+// flagging each cell that was not a given as correct, mistake, or solved
+func solveAndDiffSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+
+	sols := givens.CachedSolutions()
+	if len(sols) != 1 {
+		sudoku.Status.Message = "Status: Cannot solve and compare, the puzzle does not have a unique solution"
+		sudoku.Status.State = "invalidstatus"
+	} else {
+		correct, wrong := 0, 0
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				if givens[row][col] != 0 {
+					continue
+				}
+				subgrid := (row/3)*3 + col/3
+				name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+				cell := sudoku.Grid[name]
+				switch d := board[row][col]; {
+				case d == 0:
+					cell.Diff = "solved"
+				case d == sols[0][row][col]:
+					cell.Diff = "correct"
+					correct++
+				default:
+					cell.Diff = "mistake"
+					wrong++
+				}
+				cell.Value = strconv.Itoa(sols[0][row][col])
+				sudoku.Grid[name] = cell
+			}
+		}
+		sudoku.Status.Message = fmt.Sprintf("Status: Solved, correct entries %d, wrong entries %d", correct, wrong)
+		sudoku.Status.State = "solvedstatus"
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
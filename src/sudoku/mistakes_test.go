@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMistakes(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	right := puzzle
+	right[0][2], right[0][3] = 4, 6
+	oneWrong := right
+	oneWrong[0][5] = 1
+	twoWrong := oneWrong
+	twoWrong[8][0] = 9
+	var empty, entries Grid
+	entries[0][0] = 1
+	tests := []struct {
+		name   string
+		givens Grid
+		board  Grid
+		n      int
+		ok     bool
+		status string
+	}{
+		{"no entries", puzzle, puzzle, 0, true, "Status: No mistakes so far"},
+		{"right entries", puzzle, right, 0, true, "Status: No mistakes so far"},
+		{"one wrong", puzzle, oneWrong, 1, true, "Status: 1 entry is wrong"},
+		{"two wrong", puzzle, twoWrong, 2, true, "Status: 2 entries are wrong"},
+		{"several solutions", empty, entries, 0, false, "Status: Cannot count mistakes"},
+	}
+	for _, tt := range tests {
+		if n, ok := tt.board.Mistakes(tt.givens); n != tt.n || ok != tt.ok {
+			t.Errorf("%s: Mistakes = %d, %v, want %d, %v", tt.name, n, ok, tt.n, tt.ok)
+		}
+		rec := postForm("countmistakes", boardForm(tt.givens, tt.board))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, tt.status) {
+			t.Errorf("%s: page does not show %q", tt.name, tt.status)
+		}
+		// the count never says which entries are wrong
+		if n := strings.Count(body, `class="valid"`); n != rows*cols {
+			t.Errorf("%s: page marks %d cells", tt.name, rows*cols-n)
+		}
+	}
+}
+
+func TestSolveAndDiff(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	board := puzzle
+	board[0][2], board[0][3] = 4, 6
+	board[0][5] = 1
+	var empty Grid
+	tests := []struct {
+		name                   string
+		givens, board          Grid
+		correct, wrong, solved int
+		status                 string
+	}{
+		{"no entries", puzzle, puzzle, 0, 0, 51, "Status: Solved, correct entries 0, wrong entries 0"},
+		{"entries", puzzle, board, 2, 1, 48, "Status: Solved, correct entries 2, wrong entries 1"},
+		{"several solutions", empty, empty, 0, 0, 0, "Status: Cannot solve and compare"},
+	}
+	for _, tt := range tests {
+		rec := postForm("solveanddiff", boardForm(tt.givens, tt.board))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, tt.status) {
+			t.Errorf("%s: page does not show %q", tt.name, tt.status)
+		}
+		correct, wrong, solved := strings.Count(body, ` correct"`), strings.Count(body, ` mistake"`), strings.Count(body, ` solved"`)
+		if correct != tt.correct || wrong != tt.wrong || solved != tt.solved {
+			t.Errorf("%s: %d correct, %d mistake, %d solved cells, want %d, %d, %d",
+				tt.name, correct, wrong, solved, tt.correct, tt.wrong, tt.solved)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*
+ Move log of a game in progress.
+ Every evaluate submission compares the board with the one rebuilt from
+ the givens and the log so far, and appends a Move for each cell the
+ player changed.  The log travels with the form in a hidden field as
+ moves separated by semicolons, each "row,col,old,new,unixmillis".
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Move is one change the player made to a cell, 0 is an empty cell
+type Move struct {
+	Row  int   `json:"row"`
+	Col  int   `json:"col"`
+	Old  int   `json:"old"`
+	New  int   `json:"new"`
+	Time int64 `json:"time"` // Unix milliseconds
+}
+
+// MoveLog holds the moves of a game in the order they were made
+type MoveLog []Move
+
+// maxMoves is the longest move log accepted, as the log comes from the
+// client and each move costs a board scan in Heatmap
+const maxMoves = 2000
+
+// ParseMoveLog reads the hidden form field format of a move log.  A log
+// longer than maxMoves or with a move on a cell of givens is an error.
+func ParseMoveLog(s string, givens Grid) (MoveLog, error) {
+	var ml MoveLog
+	for _, f := range strings.Split(s, ";") {
+		if len(strings.TrimSpace(f)) == 0 {
+			continue
+		}
+		if len(ml) == maxMoves {
+			return nil, fmt.Errorf("move log is longer than %d moves", maxMoves)
+		}
+		var m Move
+		if n, err := fmt.Sscanf(f, "%d,%d,%d,%d,%d", &m.Row, &m.Col, &m.Old, &m.New, &m.Time); n != 5 || err != nil {
+			return nil, fmt.Errorf("move %q must look like row,col,old,new,unixmillis", f)
+		}
+		if !inBounds(m.Row, m.Col) || m.Old < 0 || m.Old > 9 || m.New < 0 || m.New > 9 {
+			return nil, fmt.Errorf("move %q: %v", f, errOob)
+		}
+		if givens[m.Row][m.Col] != 0 {
+			return nil, fmt.Errorf("move %q changes the given at %s: %w", f, cellName(m.Row, m.Col), errFixDig)
+		}
+		ml = append(ml, m)
+	}
+	return ml, nil
+}
+
+// String returns the hidden form field format of the move log
+func (ml MoveLog) String() string {
+	s := make([]string, len(ml))
+	for i, m := range ml {
+		s[i] = fmt.Sprintf("%d,%d,%d,%d,%d", m.Row, m.Col, m.Old, m.New, m.Time)
+	}
+	return strings.Join(s, ";")
+}
+
+// Replay returns the board after each move, starting with the givens
+func (ml MoveLog) Replay(givens Grid) []Grid {
+	boards := []Grid{givens}
+	g := givens
+	for _, m := range ml {
+		g[m.Row][m.Col] = m.New
+		boards = append(boards, g)
+	}
+	return boards
+}
+
+// Board returns the board after the last move, starting with the givens
+func (ml MoveLog) Board(givens Grid) Grid {
+	g := givens
+	for _, m := range ml {
+		g[m.Row][m.Col] = m.New
+	}
+	return g
+}
+
+// Record appends a move for every cell that differs between the board
+// rebuilt from the log and the current board, in reading order
+func (ml MoveLog) Record(givens, cur Grid, now time.Time) MoveLog {
+	prev := ml.Board(givens)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if prev[row][col] != cur[row][col] {
+				ml = append(ml, Move{Row: row, Col: col, Old: prev[row][col], New: cur[row][col], Time: now.UnixMilli()})
+			}
+		}
+	}
+	return ml
+}
+
+// Heatmap returns for every cell the milliseconds between the cell
+// becoming determinable and the player filling it.  A cell is determinable
+// once it holds a naked or hidden single; a cell filled before that counts
+// from the first move.  Givens and cells left empty are -1.
+func (ml MoveLog) Heatmap(givens Grid) [rows][cols]int64 {
+	var heat [rows][cols]int64
+
+	// the move that finally filled each cell, and the first board a cell
+	// was a single on, board i being the state before move i
+	var fill, single [rows][cols]int
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			fill[row][col], single[row][col] = -1, -1
+		}
+	}
+	g := givens
+	for i := 0; i <= len(ml); i++ {
+		singles := g.singleCells()
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				if singles[row][col] && single[row][col] < 0 {
+					single[row][col] = i
+				}
+			}
+		}
+		if i == len(ml) {
+			break
+		}
+		m := ml[i]
+		g[m.Row][m.Col] = m.New
+		if m.New != 0 {
+			fill[m.Row][m.Col] = i
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			k := fill[row][col]
+			if givens[row][col] != 0 || g[row][col] == 0 || k < 0 {
+				heat[row][col] = -1
+				continue
+			}
+			// board i is first seen at the time of move i-1, the givens at
+			// the first move, and a filled cell means the log has moves
+			det := ml[0].Time
+			if i := single[row][col]; i > 0 && i <= k {
+				det = ml[i-1].Time
+			}
+			if d := ml[k].Time - det; d > 0 {
+				heat[row][col] = d
+			}
+		}
+	}
+	return heat
+}
+
+// expectedCellTime is about how long a player takes to fill one empty
+// cell of a puzzle of each difficulty
+var expectedCellTime = [...]time.Duration{
+	easy:   10 * time.Second,
+	medium: 20 * time.Second,
+	hard:   40 * time.Second,
+	expert: 80 * time.Second,
+}
+
+// SpeedRating compares the time from the first move to the last with the
+// expected time for the puzzle, expectedCellTime of its difficulty for
+// every empty cell of the givens.  Under half the expected time is fast,
+// over twice is slow.  It returns false when the log has fewer than two
+// moves, as there is then no time to measure.
+func (ml MoveLog) SpeedRating(givens Grid) (string, bool) {
+	if len(ml) < 2 {
+		return "", false
+	}
+	level := givens.gradeLevel()
+	elapsed := time.Duration(ml[len(ml)-1].Time-ml[0].Time) * time.Millisecond
+	expected := time.Duration(rows*cols-givens.Clues()) * expectedCellTime[level]
+	pace := "average"
+	switch {
+	case elapsed < expected/2:
+		pace = "fast"
+	case elapsed > expected*2:
+		pace = "slow"
+	}
+	article := "a"
+	if level == easy || level == expert {
+		article = "an"
+	}
+	return fmt.Sprintf("%s for %s %s puzzle, %v against about %v expected",
+		pace, article, difficultyNames[level], elapsed.Round(time.Second), expected), true
+}
+
+// readBoard reads the givens and the digits the player entered from the form.
+// Entries that are not digits 1-9 count as empty cells.  A given that is not
+// a digit 1-9, which only a tampered form can hold, is an error.
+func readBoard(r *http.Request) (givens, board Grid, err error) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+			if val := r.FormValue(name + "_ro"); len(val) > 0 {
+				d, err := strconv.Atoi(val)
+				if err != nil || !validDigit(d) {
+					return givens, board, fmt.Errorf("given %s is %q: %w", cellName(row, col), val, errInvalDig)
+				}
+				givens[row][col] = d
+				board[row][col] = d
+			} else if val := r.FormValue(name); len(val) == 1 && val[0] >= '1' && val[0] <= '9' {
+				board[row][col] = int(val[0] - '0')
+			}
+		}
+	}
+	return givens, board, nil
+}
+
+// replaySudokuSubmit processes the Sudoku form submission for the replay option,
+// returning the moves and the board after each of them as JSON
+func replaySudokuSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, _, err := readBoard(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ml, err := ParseMoveLog(r.FormValue("movelog"), givens)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var resp struct {
+		Moves  MoveLog  `json:"moves"`
+		Boards []string `json:"boards"`
+	}
+	resp.Moves = append(MoveLog{}, ml...)
+	for _, g := range ml.Replay(givens) {
+		resp.Boards = append(resp.Boards, g.String())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// heatmapSudokuSubmit processes the Sudoku form submission for the heatmap
+// option, returning the time taken to fill each cell as JSON
+func heatmapSudokuSubmit(w http.ResponseWriter, r *http.Request) {
+	givens, _, err := readBoard(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ml, err := ParseMoveLog(r.FormValue("movelog"), givens)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var resp struct {
+		Heatmap [rows][cols]int64 `json:"heatmap"` // milliseconds, -1 for givens and empty cells
+	}
+	resp.Heatmap = ml.Heatmap(givens)
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadBoard(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name    string
+		field   string
+		val     string
+		wantErr bool
+	}{
+		{"puzzle", "", "", false},
+		{"entry", "0_2_0", "4", false},
+		{"bad entry is empty", "0_2_0", "x", false},
+		{"given 10", "0_0_0_ro", "10", true},
+		{"given 0", "0_0_0_ro", "0", true},
+		{"given -1", "0_0_0_ro", "-1", true},
+		{"given letter", "0_0_0_ro", "a", true},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		if tt.field != "" {
+			form.Set(tt.field, tt.val)
+		}
+		req := httptest.NewRequest(http.MethodPost, patternSubmit, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		givens, board, err := readBoard(req)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			if !errors.Is(err, errInvalDig) {
+				t.Errorf("%s: error %v, want %v", tt.name, err, errInvalDig)
+			}
+			continue
+		}
+		if givens != puzzle {
+			t.Errorf("%s: givens %s, want %s", tt.name, givens, puzzle)
+		}
+		if want := tt.val == "4"; (board[0][2] == 4) != want {
+			t.Errorf("%s: board %s", tt.name, board)
+		}
+	}
+}
+
+// TestBadGivens posts givens outside 1-9 with every form action that
+// reads them, none of which may panic or accept them.  Reset only echoes
+// the givens and new replaces them.
+func TestBadGivens(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	for _, given := range []string{"10", "-1", "a"} {
+		for _, action := range formActions {
+			if action == "reset" || action == "new" {
+				continue
+			}
+			form := boardForm(puzzle, puzzle)
+			form.Set("0_0_0_ro", given)
+			if rec := postForm(action, form); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s with given %q: status code %d, want 400", action, given, rec.Code)
+			}
+		}
+	}
+}
+
+func TestHeatmapMalformed(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tampered := puzzle
+	tampered[0][0], tampered[0][1] = -1, 10
+	ml := MoveLog{{Row: 0, Col: 2, Old: 0, New: 4, Time: 1000}, {Row: 0, Col: 3, Old: 0, New: 6, Time: 4000}}
+	for _, givens := range []Grid{puzzle, tampered} {
+		heat := ml.Heatmap(givens)
+		if heat[0][0] != -1 || heat[0][2] < 0 || heat[0][3] < 0 {
+			t.Errorf("Heatmap(%s) = %v", givens, heat[0][:4])
+		}
+	}
+
+	tests := []struct {
+		given string
+		code  int
+	}{
+		{"5", http.StatusOK},
+		{"-1", http.StatusBadRequest},
+		{"10", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		form.Set("0_0_0_ro", tt.given)
+		form.Set("movelog", ml.String())
+		if rec := postForm("heatmap", form); rec.Code != tt.code {
+			t.Errorf("heatmap with given %q: status code %d, want %d", tt.given, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	givens := mustGrid(t, testPuzzle)
+	// 0,5 is a single from the start, 0,2 is never one before it is filled
+	ml := MoveLog{
+		{Row: 0, Col: 3, Old: 0, New: 6, Time: 1000},
+		{Row: 0, Col: 5, Old: 0, New: 8, Time: 3000},
+		{Row: 1, Col: 1, Old: 0, New: 7, Time: 4000},
+		{Row: 0, Col: 2, Old: 0, New: 4, Time: 7000},
+		{Row: 1, Col: 1, Old: 7, New: 0, Time: 9000},
+	}
+	heat := ml.Heatmap(givens)
+	tests := []struct {
+		name     string
+		row, col int
+		want     int64
+	}{
+		{"given", 0, 0, -1},
+		{"never filled", 8, 0, -1},
+		{"erased", 1, 1, -1},
+		{"first move", 0, 3, 0},
+		{"single from the start", 0, 5, 2000},
+		{"not a single", 0, 2, 6000},
+	}
+	for _, tt := range tests {
+		if got := heat[tt.row][tt.col]; got != tt.want {
+			t.Errorf("%s: heat %d,%d = %d, want %d", tt.name, tt.row, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoveLog(t *testing.T) {
+	// the longest log accepted toggles R1C3, one move more is too long
+	var longest MoveLog
+	for i := 0; i < maxMoves; i++ {
+		longest = append(longest, Move{0, 2, 4 * (i % 2), 4 * ((i + 1) % 2), int64(i)})
+	}
+	tooLong := append(longest, Move{0, 2, 0, 4, maxMoves})
+	tests := []struct {
+		in      string
+		want    MoveLog
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"0,2,0,4,1000", MoveLog{{0, 2, 0, 4, 1000}}, false},
+		{"0,2,0,4,1000;0,2,4,0,2000; ", MoveLog{{0, 2, 0, 4, 1000}, {0, 2, 4, 0, 2000}}, false},
+		{"0,2,0,4", nil, true},
+		{"9,2,0,4,1000", nil, true},
+		{"0,2,0,10,1000", nil, true},
+		{"0,2,-1,4,1000", nil, true},
+		{"0,0,5,4,1000", nil, true},
+		{"0,2,0,4,1000;0,1,3,0,2000", nil, true},
+		{longest.String(), longest, false},
+		{tooLong.String(), nil, true},
+	}
+	givens := mustGrid(t, testPuzzle)
+	for i, tt := range tests {
+		ml, err := ParseMoveLog(tt.in, givens)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("case %d: ParseMoveLog error %v, want error %v", i, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(ml, tt.want) {
+			t.Errorf("case %d: ParseMoveLog = %v, want %v", i, ml, tt.want)
+		}
+		if err == nil && tt.want != nil && ml.String() != strings.TrimRight(tt.in, "; ") {
+			t.Errorf("case %d: ParseMoveLog(%q).String() = %q", i, tt.in, ml.String())
+		}
+	}
+}
+
+func TestMoveLogRecord(t *testing.T) {
+	givens := mustGrid(t, testPuzzle)
+	now := time.UnixMilli(5000)
+	b1 := givens
+	b1[0][2], b1[0][3] = 4, 6
+	b2 := b1
+	b2[0][3] = 0
+	tests := []struct {
+		name  string
+		ml    MoveLog
+		board Grid
+		want  MoveLog
+	}{
+		{"no change", nil, givens, nil},
+		{"two entries", nil, b1, MoveLog{{0, 2, 0, 4, 5000}, {0, 3, 0, 6, 5000}}},
+		{"erase", MoveLog{{0, 2, 0, 4, 1000}, {0, 3, 0, 6, 1000}}, b2,
+			MoveLog{{0, 2, 0, 4, 1000}, {0, 3, 0, 6, 1000}, {0, 3, 6, 0, 5000}}},
+	}
+	for _, tt := range tests {
+		got := tt.ml.Record(givens, tt.board, now)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Record = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		boards := got.Replay(givens)
+		if len(boards) != len(got)+1 || boards[0] != givens || boards[len(boards)-1] != tt.board {
+			t.Errorf("%s: Replay does not rebuild the board", tt.name)
+		}
+	}
+}
+
+func TestReplaySubmit(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name    string
+		movelog string
+		code    int
+		boards  int
+	}{
+		{"two moves", "0,2,0,4,1000;0,3,0,6,2000", http.StatusOK, 3},
+		{"empty", "", http.StatusOK, 1},
+		{"bad log", "0,2,0", http.StatusBadRequest, 0},
+		{"move on a given", "0,2,0,4,1000;0,0,5,9,2000", http.StatusBadRequest, 0},
+		{"too long", strings.Repeat("0,2,0,4,1000;", maxMoves+1), http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		form.Set("movelog", tt.movelog)
+		rec := postForm("replay", form)
+		if heat := postForm("heatmap", form); heat.Code != tt.code {
+			t.Errorf("%s: heatmap status code %d, want %d", tt.name, heat.Code, tt.code)
+		}
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Boards []string `json:"boards"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Boards) != tt.boards || resp.Boards[0] != testPuzzle {
+			t.Errorf("%s: boards %v, %v, want %d starting with the puzzle", tt.name, resp.Boards, err, tt.boards)
+		}
+	}
+}
+
+func TestEvaluateMoveLog(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	board := puzzle
+	board[0][2] = 4
+	form := boardForm(puzzle, board)
+	form.Set("movelog", "0,3,0,6,1000;0,3,6,0,2000")
+	body := postForm("evaluate", form).Body.String()
+	if !strings.Contains(body, `name="movelog" value="0,3,0,6,1000;0,3,6,0,2000;0,2,0,4,`) {
+		t.Errorf("evaluate does not echo the move log with the new entry")
+	}
+
+	// a forged log is dropped rather than replayed over the givens
+	for _, movelog := range []string{"0,0,5,9,1000", strings.Repeat("0,3,0,6,1000;", maxMoves+1)} {
+		form.Set("movelog", movelog)
+		rec := postForm("evaluate", form)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `name="movelog" value=""`) {
+			t.Errorf("evaluate keeps a forged move log of %d bytes", len(movelog))
+		}
+	}
+}
+
+func TestSpeedRating(t *testing.T) {
+	gen, ok := generate(context.Background(), rand.New(rand.NewSource(1)), hard)
+	if !ok {
+		t.Fatal("no hard puzzle generated")
+	}
+	hardPuzzle := mustGrid(t, gen.Puzzle)
+	// solved took elapsed from the first move to the last
+	solved := func(elapsed time.Duration) MoveLog {
+		return MoveLog{{0, 2, 0, 4, 1000}, {0, 3, 0, 6, 1000 + elapsed.Milliseconds()}}
+	}
+	tests := []struct {
+		name   string
+		ml     MoveLog
+		givens Grid
+		ok     bool
+		want   string // prefix of the rating
+	}{
+		{"fast hard", solved(time.Minute), hardPuzzle, true, "fast for a hard puzzle, 1m0s against about "},
+		{"slow easy", solved(time.Hour), mustGrid(t, testPuzzle), true, "slow for an easy puzzle, 1h0m0s against about 8m30s expected"},
+		{"average easy", solved(8 * time.Minute), mustGrid(t, testPuzzle), true, "average for an easy puzzle"},
+		{"fast expert", solved(time.Minute), mustGrid(t, testStalls), true, "fast for an expert puzzle, 1m0s against about 1h17m20s expected"},
+		{"one move", MoveLog{{0, 2, 0, 4, 1000}}, mustGrid(t, testPuzzle), false, ""},
+		{"no moves", nil, mustGrid(t, testPuzzle), false, ""},
+	}
+	for _, tt := range tests {
+		got, ok := tt.ml.SpeedRating(tt.givens)
+		if ok != tt.ok || !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: SpeedRating = %q, %v, want %q..., %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ Consistency check of the player's pencil-mark notes.
+ Notes use the constraint syntax, r4c2=13 for the digits noted in a cell.
+ Notes are expected to keep every candidate the board still allows; a
+ note that drops the digit of the solution is a mistake that makes the
+ puzzle unsolvable from the notes.
+*/
+
+package main
+
+import (
+	"math/bits"
+	"net/http"
+)
+
+const patternCheckNotes = "/api/check-notes" // pencil marks against candidates and solution
+
+// NoteWarning is a cell whose notes dropped digits that are still possible
+type NoteWarning struct {
+	Row            int   `json:"row"`
+	Col            int   `json:"col"`
+	Missing        []int `json:"missing"`        // candidates erased from the notes
+	SolutionErased bool  `json:"solutionErased"` // the solution digit is among them
+}
+
+// CheckNotes compares the notes of the empty cells with their candidates
+// and the unique solution of the grid, returning a warning for each cell
+// missing a candidate.  It returns false when the grid does not have a
+// unique solution.
+func (g Grid) CheckNotes(notes Constraints) ([]NoteWarning, bool) {
+	sols := g.CachedSolutions()
+	if len(sols) != 1 {
+		return nil, false
+	}
+	warnings := []NoteWarning{}
+	cand := g.CandidateSnapshot()
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] != 0 || notes[row][col] == 0 {
+				continue
+			}
+			missing := cand[row][col] &^ notes[row][col]
+			if missing == 0 {
+				continue
+			}
+			w := NoteWarning{Row: row, Col: col, SolutionErased: missing&(1<<sols[0][row][col]) != 0}
+			for missing != 0 {
+				d := bits.TrailingZeros16(missing)
+				w.Missing = append(w.Missing, d)
+				missing &^= 1 << d
+			}
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings, true
+}
+
+// handleCheckNotes checks the notes of the current board
+func handleCheckNotes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Puzzle string `json:"puzzle"` // givens and entries
+		Notes  string `json:"notes"`  // r4c2=13, r1c9=579
+	}
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	g, err := ParseGrid(req.Puzzle)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	notes, err := ParseConstraints(req.Notes)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] != 0 && notes[row][col] != 0 {
+				writeJSONError(w, http.StatusBadRequest, "notes given for filled cell "+cellName(row, col))
+				return
+			}
+		}
+	}
+	warnings, ok := g.CheckNotes(notes)
+	if !ok {
+		writeJSONError(w, http.StatusUnprocessableEntity, "puzzle does not have a unique solution")
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Consistent bool          `json:"consistent"`
+		Warnings   []NoteWarning `json:"warnings"`
+	}{len(warnings) == 0, warnings})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestCheckNotes(t *testing.T) {
+	// R1C3 of the puzzle has candidates 1, 2, and 4, and 4 solves it
+	tests := []struct {
+		name     string
+		notes    string
+		warnings []NoteWarning
+	}{
+		{"no notes", "", []NoteWarning{}},
+		{"all candidates", "r1c3=124", []NoteWarning{}},
+		{"extra digit", "r1c3=1249", []NoteWarning{}},
+		{"candidate erased", "r1c3=24", []NoteWarning{{Row: 0, Col: 2, Missing: []int{1}}}},
+		{"solution erased", "r1c3=12", []NoteWarning{{Row: 0, Col: 2, Missing: []int{4}, SolutionErased: true}}},
+		{"two erased", "r1c3=4", []NoteWarning{{Row: 0, Col: 2, Missing: []int{1, 2}}}},
+	}
+	g := mustGrid(t, testPuzzle)
+	for _, tt := range tests {
+		notes, err := ParseConstraints(tt.notes)
+		if err != nil {
+			t.Fatalf("%s: ParseConstraints(%q): %v", tt.name, tt.notes, err)
+		}
+		warnings, ok := g.CheckNotes(notes)
+		if !ok || !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("%s: CheckNotes = %+v, %v, want %+v", tt.name, warnings, ok, tt.warnings)
+		}
+	}
+	if _, ok := (Grid{}).CheckNotes(Constraints{}); ok {
+		t.Errorf("empty grid: CheckNotes found a unique solution")
+	}
+}
+
+func TestHandleCheckNotes(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		code       int
+		consistent bool
+	}{
+		{"consistent", `{"puzzle":"` + testPuzzle + `","notes":"r1c3=124"}`, http.StatusOK, true},
+		{"erased", `{"puzzle":"` + testPuzzle + `","notes":"r1c3=12"}`, http.StatusOK, false},
+		{"filled cell", `{"puzzle":"` + testPuzzle + `","notes":"r1c1=5"}`, http.StatusBadRequest, false},
+		{"bad notes", `{"puzzle":"` + testPuzzle + `","notes":"r1c3"}`, http.StatusBadRequest, false},
+		{"bad puzzle", `{"puzzle":"53","notes":""}`, http.StatusBadRequest, false},
+		{"several solutions", `{"puzzle":"` + Grid{}.String() + `","notes":""}`, http.StatusUnprocessableEntity, false},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Consistent bool          `json:"consistent"`
+			Warnings   []NoteWarning `json:"warnings"`
+		}
+		code := callAPI(t, handleCheckNotes, http.MethodPost, patternCheckNotes, tt.body, &resp)
+		if code != tt.code || code == http.StatusOK && (resp.Consistent != tt.consistent || resp.Consistent != (len(resp.Warnings) == 0)) {
+			t.Errorf("%s: status code %d with %+v, want %d and consistent %v", tt.name, code, resp, tt.code, tt.consistent)
+		}
+	}
+}
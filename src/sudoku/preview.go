@@ -0,0 +1,66 @@
+/*
+ Preview of a hypothetical placement.
+ The UI can show on hover which peers would lose a candidate if a digit
+ were placed in a cell, without changing the puzzle.
+*/
+
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const patternPreview = "/api/preview" // candidate eliminations of a placement
+
+// Preview returns the candidate eliminations that placing digit d in the
+// empty cell at row, col would cause in its peers, in peer order
+func (g Grid) Preview(row, col, d int) []Elimination {
+	snap := g.CandidateSnapshot()
+	elims := []Elimination{}
+	for _, p := range peers(row, col) {
+		if snap[p[0]][p[1]]&(1<<d) != 0 {
+			elims = append(elims, Elimination{Row: p[0], Col: p[1], Value: d})
+		}
+	}
+	return elims
+}
+
+// handlePreview returns the peers whose candidates would change if the
+// value query parameter were placed at row and col, counted from 0
+func handlePreview(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	g, err := ParseGrid(q.Get("puzzle"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	row, errRow := strconv.Atoi(q.Get("row"))
+	col, errCol := strconv.Atoi(q.Get("col"))
+	value, errValue := strconv.Atoi(q.Get("value"))
+	switch {
+	case errRow != nil || errCol != nil || errValue != nil:
+		writeJSONError(w, http.StatusBadRequest, "row, col, and value must be integers")
+		return
+	case !inBounds(row, col):
+		writeJSONError(w, http.StatusBadRequest, errOob.Error())
+		return
+	case !validDigit(value):
+		writeJSONError(w, http.StatusBadRequest, errInvalDig.Error())
+		return
+	case g[row][col] != 0:
+		writeJSONError(w, http.StatusBadRequest, cellName(row, col)+" is already filled")
+		return
+	case g.Candidates(row, col)&(1<<value) == 0:
+		writeJSONError(w, http.StatusBadRequest, strconv.Itoa(value)+" is not a candidate of "+cellName(row, col))
+		return
+	}
+
+	var resp struct {
+		Placement    Clue          `json:"placement"`
+		Eliminations []Elimination `json:"eliminations"`
+	}
+	resp.Placement = Clue{Row: row, Col: col, Value: value}
+	resp.Eliminations = g.Preview(row, col, value)
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	g := mustGrid(t, testPuzzle)
+	tests := []struct {
+		row, col, d int
+	}{
+		{0, 2, 4},
+		{0, 2, 1},
+		{4, 4, 5},
+		{8, 0, 3},
+	}
+	for _, tt := range tests {
+		// peers that are empty and still have d as a candidate
+		want := 0
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				peer := row == tt.row || col == tt.col || row/3 == tt.row/3 && col/3 == tt.col/3
+				if peer && (row != tt.row || col != tt.col) && g[row][col] == 0 && g.Candidates(row, col)&(1<<tt.d) != 0 {
+					want++
+				}
+			}
+		}
+		elims := g.Preview(tt.row, tt.col, tt.d)
+		if len(elims) != want {
+			t.Errorf("Preview(%d, %d, %d) = %v, want %d eliminations", tt.row, tt.col, tt.d, elims, want)
+		}
+		for _, e := range elims {
+			if e.Value != tt.d || g[e.Row][e.Col] != 0 || g.Candidates(e.Row, e.Col)&(1<<tt.d) == 0 {
+				t.Errorf("Preview(%d, %d, %d): elimination %+v", tt.row, tt.col, tt.d, e)
+			}
+		}
+	}
+}
+
+func TestHandlePreview(t *testing.T) {
+	tests := []struct {
+		name          string
+		row, col, val string
+		code          int
+	}{
+		{"candidate", "0", "2", "4", http.StatusOK},
+		{"not a candidate", "0", "2", "5", http.StatusBadRequest},
+		{"filled", "0", "0", "5", http.StatusBadRequest},
+		{"out of bounds", "9", "0", "1", http.StatusBadRequest},
+		{"digit 0", "0", "2", "0", http.StatusBadRequest},
+		{"not an integer", "a", "2", "4", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		q := url.Values{"puzzle": {testPuzzle}, "row": {tt.row}, "col": {tt.col}, "value": {tt.val}}
+		var resp struct {
+			Placement    Clue          `json:"placement"`
+			Eliminations []Elimination `json:"eliminations"`
+		}
+		code := callAPI(t, handlePreview, http.MethodGet, patternPreview+"?"+q.Encode(), "", &resp)
+		if code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, code, tt.code)
+			continue
+		}
+		if code == http.StatusOK && (resp.Placement != Clue{Row: 0, Col: 2, Value: 4} || len(resp.Eliminations) == 0) {
+			t.Errorf("%s: placement %+v with eliminations %v", tt.name, resp.Placement, resp.Eliminations)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+/*
+ Printable page of a puzzle and its solution.
+ The puzzle is drawn on top and the solution below it, small enough that
+ both fit on one printed sheet, with the solution kept from splitting
+ across pages.
+*/
+
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+const (
+	patternPrint = "/sudoku-print"        // puzzle and solution on one sheet
+	printTmpl    = "templates/print.html" // print template in assets
+)
+
+// handlePrint renders the puzzle query parameter and its solution as a
+// print-optimized HTML page
+func handlePrint(w http.ResponseWriter, r *http.Request) {
+	g, err := ParseGrid(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sol, ok := g.Solve()
+	if !ok {
+		http.Error(w, "puzzle has no solution", http.StatusUnprocessableEntity)
+		return
+	}
+	page := struct {
+		Puzzle, Solution Grid
+		Grade            string
+	}{g, sol, g.Grade()}
+	if err := tprint.Execute(w, page); err != nil {
+		log.Printf("Write print page error: %v\n", err)
+	}
+}
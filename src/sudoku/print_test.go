@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// cellRe matches a cell of a printed grid, the digit empty for a blank
+var cellRe = regexp.MustCompile(`<td>(\d?)</td>`)
+
+func TestHandlePrint(t *testing.T) {
+	tests := []struct {
+		name   string
+		puzzle string
+		code   int
+	}{
+		{"puzzle", testPuzzle, http.StatusOK},
+		{"solved", testSolution, http.StatusOK},
+		{"short", "53", http.StatusBadRequest},
+		{"no solution", "123456780000000009" + strings.Repeat("0", 63), http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handlePrint(rec, httptest.NewRequest(http.MethodGet, patternPrint+"?puzzle="+tt.puzzle, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		body := rec.Body.String()
+		puzzle, solution, ok := strings.Cut(body, `<section class="solution">`)
+		if !ok || !strings.Contains(body, "break-inside: avoid") {
+			t.Errorf("%s: page has no unbreakable solution section", tt.name)
+			continue
+		}
+		var printed, solved string
+		for _, m := range cellRe.FindAllStringSubmatch(puzzle, -1) {
+			if m[1] == "" {
+				m[1] = "0"
+			}
+			printed += m[1]
+		}
+		for _, m := range cellRe.FindAllStringSubmatch(solution, -1) {
+			solved += m[1]
+		}
+		if printed != tt.puzzle {
+			t.Errorf("%s: puzzle printed as %s", tt.name, printed)
+		}
+		if solved != testSolution {
+			t.Errorf("%s: solution printed as %s, want %s", tt.name, solved, testSolution)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ Plain text rendering of the grid for terminal clients.
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const patternRenderText = "/api/render.txt" // puzzle as ASCII art
+
+// Pretty draws the grid as ASCII art with . for empty cells and lines
+// between the 3x3 subgrids
+func (g Grid) Pretty() string {
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 && row%3 == 0 {
+			b.WriteString("------+-------+------\n")
+		}
+		for col := 0; col < cols; col++ {
+			if col > 0 && col%3 == 0 {
+				b.WriteString("| ")
+			}
+			if g[row][col] == 0 {
+				b.WriteByte('.')
+			} else {
+				b.WriteByte(byte('0' + g[row][col]))
+			}
+			if col < cols-1 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// handleRenderText returns the puzzle query parameter as text, or its
+// solution when the solution query parameter is true
+func handleRenderText(w http.ResponseWriter, r *http.Request) {
+	g, err := ParseGrid(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("solution") == "true" {
+		sol, ok := g.Solve()
+		if !ok {
+			http.Error(w, "puzzle has no solution", http.StatusUnprocessableEntity)
+			return
+		}
+		g = sol
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, g.Pretty())
+}
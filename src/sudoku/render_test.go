@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPretty(t *testing.T) {
+	lines := strings.Split(mustGrid(t, testPuzzle).Pretty(), "\n")
+	tests := []struct {
+		line int
+		want string
+	}{
+		{0, "5 3 . | . 7 . | . . ."},
+		{2, ". 9 8 | . . . | . 6 ."},
+		{3, "------+-------+------"},
+		{7, "------+-------+------"},
+		{10, ". . . | . 8 . | . 7 9"},
+		{11, ""},
+	}
+	if len(lines) != 12 {
+		t.Fatalf("Pretty has %d lines, want 11 and a final newline", len(lines))
+	}
+	for _, tt := range tests {
+		if lines[tt.line] != tt.want {
+			t.Errorf("line %d = %q, want %q", tt.line, lines[tt.line], tt.want)
+		}
+	}
+}
+
+func TestHandleRenderText(t *testing.T) {
+	broken := "55" + testPuzzle[2:]
+	tests := []struct {
+		name  string
+		query url.Values
+		code  int
+		want  string // first line
+	}{
+		{"puzzle", url.Values{"puzzle": {testPuzzle}}, http.StatusOK, "5 3 . | . 7 . | . . ."},
+		{"solution", url.Values{"puzzle": {testPuzzle}, "solution": {"true"}}, http.StatusOK, "5 3 4 | 6 7 8 | 9 1 2"},
+		{"unsolvable", url.Values{"puzzle": {broken}, "solution": {"true"}}, http.StatusUnprocessableEntity, ""},
+		{"short", url.Values{"puzzle": {"53"}}, http.StatusBadRequest, ""},
+		{"missing", url.Values{}, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleRenderText(rec, httptest.NewRequest(http.MethodGet, patternRenderText+"?"+tt.query.Encode(), nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+			continue
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("%s: content type %q", tt.name, ct)
+		}
+		if first := strings.SplitN(rec.Body.String(), "\n", 2)[0]; first != tt.want {
+			t.Errorf("%s: first line %q, want %q", tt.name, first, tt.want)
+		}
+	}
+}
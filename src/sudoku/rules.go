@@ -0,0 +1,108 @@
+/*
+ Rule sets of the Sudoku grid.
+ A rule set names the groups of cells, the units, that must hold distinct
+ digits and checks a single placement against them.  The evaluator, the
+ backtracking solver, and Grid.Set all work from the rule set, so variants
+ such as X-Sudoku or Windoku only need to add units, and grids of other
+ sizes with rectangular boxes, such as 12x12 with 3x4 boxes, build their
+ units the same way.
+*/
+
+package main
+
+// Unit is a group of cells that must hold distinct digits
+type Unit struct {
+	Kind  string   // row, col, or subgrid for the standard rules
+	Num   int      // index of the unit among those of its kind
+	Cells [][2]int // row and column of each cell
+}
+
+// RuleSet is the set of units of a Sudoku variant
+type RuleSet interface {
+	Check(g *Grid, row, col, digit int) bool // digit may be placed at row, col
+	Units() []Unit
+}
+
+// StandardRules are the rules of classic Sudoku: distinct digits in
+// every row, column, and 3x3 subgrid
+type StandardRules struct{}
+
+// gridUnits returns the rows, columns, and boxes of a size by size grid
+// whose boxes are boxRows by boxCols cells, in that order.  Boxes are
+// numbered left to right and top to bottom.
+func gridUnits(size, boxRows, boxCols int) []Unit {
+	var units []Unit
+	for i := 0; i < size; i++ {
+		u := Unit{Kind: "row", Num: i}
+		for col := 0; col < size; col++ {
+			u.Cells = append(u.Cells, [2]int{i, col})
+		}
+		units = append(units, u)
+	}
+	for i := 0; i < size; i++ {
+		u := Unit{Kind: "col", Num: i}
+		for row := 0; row < size; row++ {
+			u.Cells = append(u.Cells, [2]int{row, i})
+		}
+		units = append(units, u)
+	}
+	stacks := size / boxCols // boxes across the grid
+	for i := 0; i < size; i++ {
+		u := Unit{Kind: "subgrid", Num: i}
+		r0, c0 := (i/stacks)*boxRows, (i%stacks)*boxCols
+		for row := r0; row < r0+boxRows; row++ {
+			for col := c0; col < c0+boxCols; col++ {
+				u.Cells = append(u.Cells, [2]int{row, col})
+			}
+		}
+		units = append(units, u)
+	}
+	return units
+}
+
+// standardUnits holds the rows, columns, and 3x3 subgrids in that order
+var standardUnits = gridUnits(rows, 3, 3)
+
+// Units returns the rows, columns, and subgrids
+func (StandardRules) Units() []Unit {
+	return standardUnits
+}
+
+// standardTable indexes the standard units by cell
+var standardTable = newRuleTable(StandardRules{})
+
+// Check enforces the Sudoku rules for digit uniqueness in the row, column,
+// and subgrid of the cell
+func (StandardRules) Check(g *Grid, row, col, digit int) bool {
+	for _, i := range standardTable.cellUnits[row][col] {
+		for _, rc := range standardUnits[i].Cells {
+			if g[rc[0]][rc[1]] == digit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rules are the rules of the puzzles served
+var rules RuleSet = StandardRules{}
+
+// ruleTable is a rule set indexed for the solver
+type ruleTable struct {
+	units     []Unit
+	cellUnits [rows][cols][]int // indexes of the units holding each cell
+}
+
+// newRuleTable indexes the units of the rule set by cell
+func newRuleTable(rs RuleSet) *ruleTable {
+	rt := &ruleTable{units: rs.Units()}
+	for i, u := range rt.units {
+		for _, rc := range u.Cells {
+			rt.cellUnits[rc[0]][rc[1]] = append(rt.cellUnits[rc[0]][rc[1]], i)
+		}
+	}
+	return rt
+}
+
+// table is the rule table of rules used by the solver
+var table = newRuleTable(rules)
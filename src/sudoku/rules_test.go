@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/fs"
+	"math/rand"
+	"testing"
+)
+
+func TestStandardUnits(t *testing.T) {
+	units := rules.Units()
+	if len(units) != rows+cols+subgrids {
+		t.Fatalf("got %d units, want %d", len(units), rows+cols+subgrids)
+	}
+	for _, u := range units {
+		if len(u.Cells) != 9 {
+			t.Fatalf("%s %d has %d cells, want 9", u.Kind, u.Num, len(u.Cells))
+		}
+		for _, rc := range u.Cells {
+			var in bool
+			switch u.Kind {
+			case "row":
+				in = rc[0] == u.Num
+			case "col":
+				in = rc[1] == u.Num
+			case "subgrid":
+				in = (rc[0]/3)*3+rc[1]/3 == u.Num
+			}
+			if !in {
+				t.Errorf("%s %d holds cell %v", u.Kind, u.Num, rc)
+			}
+		}
+	}
+}
+
+func TestStandardCheck(t *testing.T) {
+	g := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name     string
+		row, col int
+		digit    int
+		want     bool
+	}{
+		{"solution digit", 0, 2, 4, true},
+		{"in the row", 0, 2, 7, false},
+		{"in the column", 0, 2, 8, false},
+		{"in the subgrid", 1, 1, 8, false},
+		{"candidate", 4, 4, 5, true},
+	}
+	for _, tt := range tests {
+		if got := (StandardRules{}).Check(&g, tt.row, tt.col, tt.digit); got != tt.want {
+			t.Errorf("%s: Check(%d, %d, %d) = %v, want %v", tt.name, tt.row, tt.col, tt.digit, got, tt.want)
+		}
+	}
+}
+
+// diagonalRules adds the two main diagonals to the standard units, as in X-Sudoku
+type diagonalRules struct{ StandardRules }
+
+func (diagonalRules) Units() []Unit {
+	main, anti := Unit{Kind: "diagonal", Num: 0}, Unit{Kind: "diagonal", Num: 1}
+	for i := 0; i < rows; i++ {
+		main.Cells = append(main.Cells, [2]int{i, i})
+		anti.Cells = append(anti.Cells, [2]int{i, cols - 1 - i})
+	}
+	return append(append([]Unit{}, standardUnits...), main, anti)
+}
+
+func TestRuleTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		rs       RuleSet
+		row, col int
+		units    int
+	}{
+		{"standard", StandardRules{}, 0, 0, 3},
+		{"standard center", StandardRules{}, 4, 4, 3},
+		{"diagonal corner", diagonalRules{}, 0, 0, 4},
+		{"diagonal center", diagonalRules{}, 4, 4, 5},
+		{"diagonal off", diagonalRules{}, 0, 1, 3},
+	}
+	for _, tt := range tests {
+		rt := newRuleTable(tt.rs)
+		idx := rt.cellUnits[tt.row][tt.col]
+		if len(idx) != tt.units {
+			t.Errorf("%s: %s is in %d units, want %d", tt.name, cellName(tt.row, tt.col), len(idx), tt.units)
+		}
+		for _, i := range idx {
+			found := false
+			for _, rc := range rt.units[i].Cells {
+				found = found || rc == [2]int{tt.row, tt.col}
+			}
+			if !found {
+				t.Errorf("%s: unit %d does not hold %s", tt.name, i, cellName(tt.row, tt.col))
+			}
+		}
+	}
+}
+
+// loopCheck is the row, column, and subgrid scan that ruleCheck used before
+// the rule sets
+func loopCheck(g *Grid, row, col, digit int) bool {
+	for c := 0; c < cols; c++ {
+		if g[row][c] == digit {
+			return false
+		}
+	}
+	for r := 0; r < rows; r++ {
+		if g[r][col] == digit {
+			return false
+		}
+	}
+	r0, c0 := (row/3)*3, (col/3)*3
+	for r := r0; r < r0+3; r++ {
+		for c := c0; c < c0+3; c++ {
+			if g[r][c] == digit {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestStandardCheckMatchesLoop(t *testing.T) {
+	paths, err := fs.Glob(assets, gridDir+"/*.txt")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no grid files in %s: %v", gridDir, err)
+	}
+	var boards []Grid
+	for _, path := range paths {
+		f, err := assets.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := ReadPuzzle(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		boards = append(boards, g)
+	}
+	// random boards, most of them breaking the rules
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		var g Grid
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				g[row][col] = rng.Intn(10)
+			}
+		}
+		boards = append(boards, g)
+	}
+	for n, g := range boards {
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				for d := 1; d <= 9; d++ {
+					if got, want := (StandardRules{}).Check(&g, row, col, d), loopCheck(&g, row, col, d); got != want {
+						t.Fatalf("board %d: Check(%d, %d, %d) = %v, want %v\n%v", n, row, col, d, got, want, g)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ Startup self-test of the bundled puzzles.
+ With -selftest every grid file is parsed, checked against the rules, and
+ solved before the server starts, so a corrupted fixture stops the server
+ instead of failing in front of a player.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+)
+
+var errNoSolution = errors.New("puzzle has no solution")
+
+// checkGridFile reads, checks, and solves one grid file of fsys
+func checkGridFile(fsys fs.FS, path string) error {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	g, err := ReadPuzzle(f)
+	if err != nil {
+		return err
+	}
+	if !g.GivensConsistent() {
+		return errRules
+	}
+	if _, ok := g.Solve(); !ok {
+		return errNoSolution
+	}
+	return nil
+}
+
+// gridFS returns the grid files in dir, or the bundled ones when dir is empty
+func gridFS(dir string) fs.FS {
+	if dir == "" {
+		bundled, err := fs.Sub(assets, gridDir)
+		if err != nil {
+			log.Fatalf("Bundled grid files: %v\n", err)
+		}
+		return bundled
+	}
+	return os.DirFS(dir)
+}
+
+// selfTest checks every grid file in dir, or the bundled ones when dir is
+// empty, logging the broken ones, and returns the number of broken files
+func selfTest(dir string) int {
+	fsys := gridFS(dir)
+	if dir == "" {
+		dir = "the bundled " + gridDir
+	}
+	paths, err := fs.Glob(fsys, "*.txt")
+	if err != nil || len(paths) == 0 {
+		log.Printf("Self-test: no grid files in %s\n", dir)
+		return 1
+	}
+	broken := 0
+	for _, path := range paths {
+		if err := checkGridFile(fsys, path); err != nil {
+			log.Printf("Self-test: %s: %v\n", path, err)
+			broken++
+		}
+	}
+	log.Printf("Self-test: %d of %d grid files passed\n", len(paths)-broken, len(paths))
+	return broken
+}
+
+// selfTestExit returns the exit status of the server before it starts:
+// 1 when run is set and a grid file in dir, or a bundled one when dir is
+// empty, is broken, otherwise 0 to go on serving
+func selfTestExit(run bool, dir string) int {
+	if run && selfTest(dir) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGridFiles writes each grid file content to a file in a new directory
+func writeGridFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheckGridFile(t *testing.T) {
+	var sdk []string
+	for row := 0; row < rows; row++ {
+		sdk = append(sdk, testPuzzle[row*cols:(row+1)*cols])
+	}
+	// 0,8 can only hold 9, which column 8 already has
+	noSolution := "123456780000000009" + strings.Repeat("0", 63)
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"puzzle", testPuzzle, nil},
+		{"sdk", "# comment\n" + strings.Join(sdk, "\n") + "\n", nil},
+		{"breaks the rules", "55" + testPuzzle[2:], errRules},
+		{"no solution", noSolution, errNoSolution},
+		{"short", "53", errGridFormat},
+	}
+	files := make(map[string]string)
+	for _, tt := range tests {
+		files[tt.name+".txt"] = tt.data
+	}
+	dir := writeGridFiles(t, files)
+	for _, tt := range tests {
+		err := checkGridFile(os.DirFS(dir), tt.name+".txt")
+		if (err != nil) != (tt.err != nil) || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: checkGridFile error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+	if err := checkGridFile(os.DirFS(dir), "missing.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: checkGridFile error %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		broken int
+	}{
+		{"good", map[string]string{"a.txt": testPuzzle, "b.txt": testSolution}, 0},
+		{"one broken", map[string]string{"a.txt": testPuzzle, "b.txt": "53"}, 1},
+		{"other files skipped", map[string]string{"a.txt": testPuzzle, "notes.md": "53"}, 0},
+		{"no grid files", map[string]string{"notes.md": testPuzzle}, 1},
+	}
+	for _, tt := range tests {
+		if n := selfTest(writeGridFiles(t, tt.files)); n != tt.broken {
+			t.Errorf("%s: selfTest = %d, want %d", tt.name, n, tt.broken)
+		}
+	}
+	if n := selfTest(""); n != 0 {
+		t.Errorf("bundled grid files: %d broken", n)
+	}
+}
+
+func TestSelfTestExit(t *testing.T) {
+	good := writeGridFiles(t, map[string]string{"a.txt": testPuzzle})
+	bad := writeGridFiles(t, map[string]string{"a.txt": testPuzzle, "b.txt": "55" + testPuzzle[2:]})
+	tests := []struct {
+		name string
+		run  bool
+		dir  string
+		code int
+	}{
+		{"good", true, good, 0},
+		{"one bad fixture", true, bad, 1},
+		{"no grid files", true, t.TempDir(), 1},
+		{"not run", false, bad, 0},
+		{"bundled", true, "", 0},
+	}
+	for _, tt := range tests {
+		if code := selfTestExit(tt.run, tt.dir); code != tt.code {
+			t.Errorf("%s: exit status %d, want %d", tt.name, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ Session storage for games in progress.
+ A sessionStore maps session IDs to values under a read/write lock.  Each
+ Set restarts the session's time to live, and a janitor goroutine evicts
+ the sessions that were not set again in time, so abandoned games do not
+ hold memory.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// session is one stored value and the time it expires
+type session struct {
+	value   interface{}
+	expires time.Time
+}
+
+// sessionStore is a concurrency-safe map of sessions with a time to live
+type sessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]session
+	ttl      time.Duration
+	done     chan struct{}
+}
+
+// newSessionStore returns a store whose sessions live for ttl after their
+// last Set, with a janitor evicting expired sessions every interval
+func newSessionStore(ttl, interval time.Duration) *sessionStore {
+	s := &sessionStore{sessions: make(map[string]session), ttl: ttl, done: make(chan struct{})}
+	go s.janitor(interval)
+	return s
+}
+
+// newSessionID returns a random session ID of 32 hex digits
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Get returns the value of an unexpired session
+func (s *sessionStore) Get(id string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.sessions[id]
+	if !ok || time.Now().After(sess.expires) {
+		return nil, false
+	}
+	return sess.value, true
+}
+
+// Set stores the value of a session and restarts its time to live
+func (s *sessionStore) Set(id string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = session{value: v, expires: time.Now().Add(s.ttl)}
+}
+
+// Delete removes a session
+func (s *sessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// Len returns the number of sessions held, including expired ones not yet evicted
+func (s *sessionStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions)
+}
+
+// evict removes the sessions expired at now
+func (s *sessionStore) evict(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// janitor evicts expired sessions every interval until Close
+func (s *sessionStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.evict(now)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops the janitor
+func (s *sessionStore) Close() {
+	close(s.done)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionStore(t *testing.T) {
+	s := newSessionStore(time.Hour, time.Hour)
+	defer s.Close()
+	s.Set("a", 1)
+	s.Set("b", "two")
+	s.Set("a", 3)
+	s.Delete("b")
+	tests := []struct {
+		id    string
+		value interface{}
+		ok    bool
+	}{
+		{"a", 3, true},
+		{"b", nil, false},
+		{"c", nil, false},
+	}
+	for _, tt := range tests {
+		if v, ok := s.Get(tt.id); v != tt.value || ok != tt.ok {
+			t.Errorf("Get(%q) = %v, %v, want %v, %v", tt.id, v, ok, tt.value, tt.ok)
+		}
+	}
+	if n := s.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	s := newSessionStore(time.Minute, time.Hour)
+	defer s.Close()
+	s.Set("a", 1)
+	tests := []struct {
+		name  string
+		after time.Duration
+		held  int
+	}{
+		{"before expiry", 30 * time.Second, 1},
+		{"after expiry", 2 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		s.evict(time.Now().Add(tt.after))
+		if n := s.Len(); n != tt.held {
+			t.Errorf("%s: Len = %d, want %d", tt.name, n, tt.held)
+		}
+	}
+
+	// an expired session is gone for Get before the janitor evicts it
+	short := newSessionStore(time.Millisecond, time.Hour)
+	defer short.Close()
+	short.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := short.Get("a"); ok || short.Len() != 1 {
+		t.Errorf("expired session: Get found %v, Len %d", ok, short.Len())
+	}
+
+	// the janitor evicts it
+	fast := newSessionStore(time.Millisecond, time.Millisecond)
+	defer fast.Close()
+	fast.Set("a", 1)
+	for deadline := time.Now().Add(time.Second); fast.Len() > 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if n := fast.Len(); n != 0 {
+		t.Errorf("janitor left %d sessions", n)
+	}
+}
+
+// TestSessionConcurrent runs with -race to check the store's locking
+func TestSessionConcurrent(t *testing.T) {
+	s := newSessionStore(time.Hour, time.Millisecond)
+	defer s.Close()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("%d-%d", w, i%10)
+				s.Set(id, i)
+				s.Get(id)
+				if i%3 == 0 {
+					s.Delete(id)
+				}
+				s.Len()
+			}
+		}(w)
+	}
+	wg.Wait()
+	if n := s.Len(); n > 80 {
+		t.Errorf("Len = %d, want at most 80", n)
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := newSessionID()
+		if err != nil || len(id) != 32 || seen[id] {
+			t.Fatalf("newSessionID = %q, %v", id, err)
+		}
+		seen[id] = true
+	}
+}
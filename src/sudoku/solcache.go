@@ -0,0 +1,44 @@
+/*
+ Cache of puzzle solutions.
+ The interactive features that compare the board with the solution would
+ otherwise solve the same givens on every request.  Solutions are kept per
+ set of givens, so player entries never cause a new solve while new givens
+ always do.  The key is the exact givens rather than GivensHash, since
+ puzzles equal up to symmetry share a hash but not a solution.
+*/
+
+package main
+
+import "sync"
+
+const solutionCacheSize = 1024 // most sets of givens cached at once
+
+// solutionCache holds up to two solutions for each set of givens solved so far
+var solutionCache = struct {
+	sync.Mutex
+	m map[Grid][]Grid
+}{m: make(map[Grid][]Grid)}
+
+// CachedSolutions returns up to two solutions of the givens, solving each
+// distinct set of givens once
+func (g Grid) CachedSolutions() []Grid {
+	solutionCache.Lock()
+	sols, ok := solutionCache.m[g]
+	solutionCache.Unlock()
+	if ok {
+		return sols
+	}
+
+	sols = g.Solutions(2)
+	solutionCache.Lock()
+	defer solutionCache.Unlock()
+	if len(solutionCache.m) >= solutionCacheSize {
+		// drop an arbitrary entry to make room
+		for k := range solutionCache.m {
+			delete(solutionCache.m, k)
+			break
+		}
+	}
+	solutionCache.m[g] = sols
+	return sols
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestCachedSolutions(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+		n    int
+	}{
+		{"puzzle", mustGrid(t, testPuzzle), 1},
+		{"solution", mustGrid(t, testSolution), 1},
+		{"several solutions", Grid{}, 2},
+		{"breaks the rules", mustGrid(t, "55"+testPuzzle[2:]), 0},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			sols := tt.g.CachedSolutions()
+			if len(sols) != tt.n {
+				t.Errorf("%s: call %d found %d solutions, want %d", tt.name, i+1, len(sols), tt.n)
+			}
+			for _, sol := range sols {
+				if !sol.RespectsGivens(tt.g) || sol.Clues() != rows*cols || !sol.GivensConsistent() {
+					t.Errorf("%s: call %d found %s", tt.name, i+1, sol)
+				}
+			}
+		}
+		solutionCache.Lock()
+		_, ok := solutionCache.m[tt.g]
+		solutionCache.Unlock()
+		if !ok {
+			t.Errorf("%s: givens are not cached", tt.name)
+		}
+	}
+}
+
+// TestSolutionCacheSize caches more sets of givens than the cache holds
+func TestSolutionCacheSize(t *testing.T) {
+	sol := mustGrid(t, testSolution)
+	// each pair of blanked cells is a different set of givens
+	n := 0
+	for a := 0; a < rows*cols && n < solutionCacheSize+10; a++ {
+		for b := a + 1; b < rows*cols && n < solutionCacheSize+10; b++ {
+			g := sol
+			g[a/cols][a%cols], g[b/cols][b%cols] = 0, 0
+			g.CachedSolutions()
+			n++
+		}
+	}
+	solutionCache.Lock()
+	size := len(solutionCache.m)
+	solutionCache.Unlock()
+	if size > solutionCacheSize {
+		t.Errorf("cache holds %d sets of givens, want at most %d", size, solutionCacheSize)
+	}
+}
@@ -0,0 +1,460 @@
+/*
+ Backtracking solver for the Sudoku grid.
+ Bitmasks of the digits already used in each unit of the rule set
+ let the solver choose the empty cell with the fewest candidates at
+ every step, so even sparse puzzles solve in milliseconds.  Unlike the
+ randomized trial solver used by the form handlers, it is exhaustive
+ and can count solutions, which is what uniqueness checks need.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"strings"
+)
+
+const allDigits uint16 = 0x3fe // bits 1-9 set, one per digit
+
+var (
+	errGridFormat = errors.New("grid must be 81 characters of 1-9 with 0 or . for empty cells")
+	errDepth      = errors.New("solver recursion depth limit exceeded")
+)
+
+// maxDepth is the default recursion limit of the solver.  Each level fills
+// one empty cell, so a board of any size never needs more than its cells.
+const maxDepth = rows * cols
+
+// Constraints restricts cells to a set of digits, bit d set allows digit d.
+// A zero mask leaves the cell unconstrained.
+type Constraints [rows][cols]uint16
+
+// solver holds the search state for a backtracking solve
+type solver struct {
+	g     Grid
+	allow *Constraints // optional extra restrictions on cell digits
+	rng   *rand.Rand   // optional random order for trying digits
+	rt    *ruleTable   // units of the rule set
+	used  []uint16     // digits used in each unit
+	limit int          // stop after this many solutions
+	sols  []Grid       // solutions found
+	depth int          // current recursion depth
+	max   int          // recursion depth limit
+	err   error        // errDepth when the search stopped at the limit
+}
+
+// ParseGrid converts 81 characters in row order into a Grid.
+// Digits 1-9 are givens, '0' and '.' are empty cells.
+func ParseGrid(s string) (Grid, error) {
+	var g Grid
+	s = strings.TrimSpace(s)
+	if len(s) != rows*cols {
+		return g, errGridFormat
+	}
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch >= '1' && ch <= '9':
+			g[i/cols][i%cols] = int(ch - '0')
+		case ch == '0' || ch == '.':
+		default:
+			return g, errGridFormat
+		}
+	}
+	return g, nil
+}
+
+// String returns the grid as 81 characters in row order with '0' for empty cells
+func (g Grid) String() string {
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			b.WriteByte(byte('0' + g[row][col]))
+		}
+	}
+	return b.String()
+}
+
+// Clues returns the number of filled cells in the grid
+func (g Grid) Clues() int {
+	n := 0
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] != 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// ClueDistribution returns the number of filled cells in each row,
+// column, and box, boxes numbered as in Box
+func (g Grid) ClueDistribution() (perRow, perCol, perBox [9]int) {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] != 0 {
+				perRow[row]++
+				perCol[col]++
+				perBox[(row/3)*3+col/3]++
+			}
+		}
+	}
+	return perRow, perCol, perBox
+}
+
+// Row returns the values of row i, 0 for empty cells
+func (g Grid) Row(i int) [cols]int {
+	return g.unitValues(i)
+}
+
+// Col returns the values of column j from top to bottom
+func (g Grid) Col(j int) [rows]int {
+	return g.unitValues(rows + j)
+}
+
+// Box returns the values of subgrid b in reading order, where the
+// subgrids are numbered 0-8 left to right and top to bottom
+func (g Grid) Box(b int) [subgrids]int {
+	return g.unitValues(rows + cols + b)
+}
+
+// unitValues returns the values of the cells of unit i of the standard
+// rules, which lists the rows, then the columns, then the subgrids
+func (g Grid) unitValues(i int) [9]int {
+	var u [9]int
+	for k, rc := range (StandardRules{}).Units()[i].Cells {
+		u[k] = g[rc[0]][rc[1]]
+	}
+	return u
+}
+
+// GivensConsistent reports whether the filled cells obey the Sudoku rules
+func (g Grid) GivensConsistent() bool {
+	_, ok := newSolver(g, 1)
+	return ok
+}
+
+// RespectsGivens reports whether every given of the original puzzle is
+// unchanged in the grid, so a completed board cannot win by overwriting givens
+func (g Grid) RespectsGivens(givens Grid) bool {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if givens[row][col] != 0 && g[row][col] != givens[row][col] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// newSolver loads the grid into a solver, returning false if the givens break the rules
+func newSolver(g Grid, limit int) (*solver, bool) {
+	sv := &solver{g: g, limit: limit, rt: table, used: make([]uint16, len(table.units)), max: maxDepth}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			d := g[row][col]
+			if d == 0 {
+				continue
+			}
+			if !validDigit(d) {
+				return nil, false
+			}
+			bit := uint16(1) << d
+			if sv.usedAt(row, col)&bit != 0 {
+				return nil, false
+			}
+			sv.mark(row, col, bit)
+		}
+	}
+	return sv, true
+}
+
+// usedAt returns the digits used in the units holding the cell
+func (sv *solver) usedAt(row, col int) uint16 {
+	var used uint16
+	for _, u := range sv.rt.cellUnits[row][col] {
+		used |= sv.used[u]
+	}
+	return used
+}
+
+// mark records the digit bit as used in the units holding the cell
+func (sv *solver) mark(row, col int, bit uint16) {
+	for _, u := range sv.rt.cellUnits[row][col] {
+		sv.used[u] |= bit
+	}
+}
+
+// unmark frees the digit bit in the units holding the cell
+func (sv *solver) unmark(row, col int, bit uint16) {
+	for _, u := range sv.rt.cellUnits[row][col] {
+		sv.used[u] &^= bit
+	}
+}
+
+// search fills empty cells depth first, returning true when the solution
+// limit is reached or the search stops at the depth limit
+func (sv *solver) search() bool {
+	if sv.depth > sv.max {
+		sv.err = errDepth
+		return true
+	}
+	// find the empty cell with the fewest candidates
+	bestRow, bestCol := -1, -1
+	var bestMask uint16
+	min := 10
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if sv.g[row][col] != 0 {
+				continue
+			}
+			mask := allDigits &^ sv.usedAt(row, col)
+			if sv.allow != nil && sv.allow[row][col] != 0 {
+				mask &= sv.allow[row][col]
+			}
+			n := bits.OnesCount16(mask)
+			if n < min {
+				min = n
+				bestRow, bestCol, bestMask = row, col, mask
+				if n <= 1 {
+					break
+				}
+			}
+		}
+		if min <= 1 {
+			break
+		}
+	}
+
+	// no empty cells left, record the solution
+	if bestRow < 0 {
+		sv.sols = append(sv.sols, sv.g)
+		return len(sv.sols) >= sv.limit
+	}
+
+	order := [9]int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if sv.rng != nil {
+		sv.rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+	for _, d := range order {
+		bit := uint16(1) << d
+		if bestMask&bit == 0 {
+			continue
+		}
+		sv.g[bestRow][bestCol] = d
+		sv.mark(bestRow, bestCol, bit)
+		sv.depth++
+		done := sv.search()
+		sv.depth--
+		sv.unmark(bestRow, bestCol, bit)
+		sv.g[bestRow][bestCol] = 0
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+// Solutions returns up to limit solutions of the grid
+func (g Grid) Solutions(limit int) []Grid {
+	sols, _ := g.SolutionsWithin(limit, maxDepth)
+	return sols
+}
+
+// SolutionsWithin returns up to limit solutions of the grid, or errDepth
+// with the solutions found so far when the search needs to recurse deeper
+// than depth
+func (g Grid) SolutionsWithin(limit, depth int) ([]Grid, error) {
+	sv, ok := newSolver(g, limit)
+	if !ok || limit < 1 {
+		return nil, nil
+	}
+	sv.max = depth
+	sv.search()
+	return sv.sols, sv.err
+}
+
+// CountSolutions returns the number of solutions of the grid, stopping at limit
+func (g Grid) CountSolutions(limit int) int {
+	return len(g.Solutions(limit))
+}
+
+// ConstrainedSolutions returns up to limit solutions in which every
+// cell holds one of the digits its constraint allows
+func (g Grid) ConstrainedSolutions(c *Constraints, limit int) []Grid {
+	sv, ok := newSolver(g, limit)
+	if !ok || limit < 1 {
+		return nil
+	}
+	// givens must satisfy their own constraints
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if d := g[row][col]; d != 0 && c[row][col] != 0 && c[row][col]&(1<<d) == 0 {
+				return nil
+			}
+		}
+	}
+	sv.allow = c
+	sv.search()
+	return sv.sols
+}
+
+// ParseConstraints reads constraints such as "r4c2=13, r1c9=579" where
+// row and column are 1-9 and the digits after = are the allowed values
+func ParseConstraints(s string) (Constraints, error) {
+	var c Constraints
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	for _, f := range fields {
+		var row, col int
+		var digits string
+		if n, err := fmt.Sscanf(strings.ToLower(f), "r%1dc%1d=%s", &row, &col, &digits); n != 3 || err != nil {
+			return c, fmt.Errorf("constraint %q must look like r4c2=13", f)
+		}
+		if !inBounds(row-1, col-1) {
+			return c, fmt.Errorf("constraint %q: %v", f, errOob)
+		}
+		for _, ch := range digits {
+			if ch < '1' || ch > '9' {
+				return c, fmt.Errorf("constraint %q: %v", f, errInvalDig)
+			}
+			c[row-1][col-1] |= 1 << (ch - '0')
+		}
+	}
+	return c, nil
+}
+
+// Solve returns the first solution of the grid and whether one exists
+func (g Grid) Solve() (Grid, bool) {
+	sols := g.Solutions(1)
+	if len(sols) == 0 {
+		return g, false
+	}
+	return sols[0], true
+}
+
+// RandomSolution returns a complete valid grid chosen by rng
+func RandomSolution(rng *rand.Rand) Grid {
+	sv, _ := newSolver(Grid{}, 1)
+	sv.rng = rng
+	sv.search()
+	return sv.sols[0]
+}
+
+// Ambiguity is a clue whose removal lets the puzzle have a second
+// solution, with the digit the removed cell holds in that solution
+type Ambiguity struct {
+	Clue
+	Alternate int `json:"alternate"`
+}
+
+// RemovalAmbiguity reports whether removing the clue at row, col from a
+// uniquely solvable grid allows a second solution, and which one
+func (g Grid) RemovalAmbiguity(row, col int) (Ambiguity, bool) {
+	d := g[row][col]
+	if d == 0 {
+		return Ambiguity{}, false
+	}
+	g[row][col] = 0
+	for _, sol := range g.Solutions(2) {
+		// every other solution differs in the removed cell, or it
+		// would also solve the puzzle with the clue in place
+		if sol[row][col] != d {
+			return Ambiguity{Clue: Clue{Row: row, Col: col, Value: d}, Alternate: sol[row][col]}, true
+		}
+	}
+	return Ambiguity{}, false
+}
+
+// RedundantClues returns the clues of a uniquely solvable grid that could
+// each be removed on its own without allowing a second solution.  A grid
+// with none is minimal.
+func (g Grid) RedundantClues() []Clue {
+	clues := []Clue{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] == 0 {
+				continue
+			}
+			if _, ok := g.RemovalAmbiguity(row, col); !ok {
+				clues = append(clues, Clue{Row: row, Col: col, Value: g[row][col]})
+			}
+		}
+	}
+	return clues
+}
+
+// ForcedCells returns the empty cells, in reading order, that hold the
+// same digit in every solution of the grid.  Two solutions rule out the
+// cells where they differ, and each cell left is then checked by looking
+// for a solution with a different digit there.  A grid with no solution
+// has no forced cells.
+func (g Grid) ForcedCells() [][2]int {
+	sols := g.Solutions(2)
+	if len(sols) == 0 {
+		return nil
+	}
+	first := sols[0]
+	var free [rows][cols]bool
+	for _, sol := range sols[1:] {
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				free[row][col] = free[row][col] || sol[row][col] != first[row][col]
+			}
+		}
+	}
+	forced := [][2]int{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if g[row][col] != 0 || free[row][col] {
+				continue
+			}
+			var c Constraints
+			c[row][col] = allDigits &^ (1 << first[row][col])
+			alt := g.ConstrainedSolutions(&c, 1)
+			if len(alt) == 0 {
+				forced = append(forced, [2]int{row, col})
+				continue
+			}
+			// the other solution frees every cell where it differs
+			for r := 0; r < rows; r++ {
+				for k := 0; k < cols; k++ {
+					free[r][k] = free[r][k] || alt[0][r][k] != first[r][k]
+				}
+			}
+		}
+	}
+	return forced
+}
+
+// Minimize removes clues in an order chosen by rng while the puzzle keeps
+// a unique solution.  Every clue left is needed: removing any one of them
+// would allow a second solution.  The grid must already be uniquely solvable.
+func (g Grid) Minimize(rng *rand.Rand) Grid {
+	min, _ := g.MinimizeReport(rng)
+	return min
+}
+
+// MinimizeReport minimizes like Minimize and also returns the first clue
+// kept because its removal allowed a second solution, nil if none was
+func (g Grid) MinimizeReport(rng *rand.Rand) (Grid, *Ambiguity) {
+	var first *Ambiguity
+	for _, i := range rng.Perm(rows * cols) {
+		row, col := i/cols, i%cols
+		if g[row][col] == 0 {
+			continue
+		}
+		if amb, ok := g.RemovalAmbiguity(row, col); ok {
+			if first == nil {
+				first = &amb
+			}
+			continue
+		}
+		g[row][col] = 0
+	}
+	return g, first
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGrid(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{testPuzzle, testPuzzle, false},
+		{strings.Replace(testPuzzle, "0", ".", -1), testPuzzle, false},
+		{" " + testPuzzle + "\n", testPuzzle, false},
+		{testPuzzle[:80], "", true},
+		{"x" + testPuzzle[1:], "", true},
+	}
+	for _, tt := range tests {
+		g, err := ParseGrid(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGrid(%.12q...) error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && g.String() != tt.want {
+			t.Errorf("ParseGrid(%.12q...) = %s, want %s", tt.in, g, tt.want)
+		}
+	}
+}
+
+func TestSolutions(t *testing.T) {
+	conflict := mustGrid(t, testPuzzle)
+	conflict[0][2] = 5
+	tests := []struct {
+		name  string
+		g     Grid
+		limit int
+		want  int
+	}{
+		{"unique", mustGrid(t, testPuzzle), 2, 1},
+		{"empty", Grid{}, 2, 2},
+		{"empty limit 1", Grid{}, 1, 1},
+		{"conflict", conflict, 2, 0},
+		{"limit 0", mustGrid(t, testPuzzle), 0, 0},
+	}
+	for _, tt := range tests {
+		sols := tt.g.Solutions(tt.limit)
+		if len(sols) != tt.want {
+			t.Errorf("%s: %d solutions, want %d", tt.name, len(sols), tt.want)
+		}
+		for _, s := range sols {
+			if s.Clues() != rows*cols || !s.GivensConsistent() || !s.RespectsGivens(tt.g) {
+				t.Errorf("%s: %s is not a solution", tt.name, s)
+			}
+		}
+	}
+	if sols := mustGrid(t, testPuzzle).Solutions(1); sols[0].String() != testSolution {
+		t.Errorf("solution %s, want %s", sols[0], testSolution)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+		seed int64
+	}{
+		{"solution", mustGrid(t, testSolution), 1},
+		{"solution other seed", mustGrid(t, testSolution), 2},
+		{"puzzle", mustGrid(t, testPuzzle), 1},
+	}
+	for _, tt := range tests {
+		min := tt.g.Minimize(rand.New(rand.NewSource(tt.seed)))
+		if n := min.CountSolutions(2); n != 1 || !tt.g.RespectsGivens(min) {
+			t.Errorf("%s: %s has %d solutions or adds clues", tt.name, min, n)
+			continue
+		}
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				if min[row][col] == 0 {
+					continue
+				}
+				less := min
+				less[row][col] = 0
+				if less.CountSolutions(2) == 1 {
+					t.Errorf("%s: clue %s of %s is not needed", tt.name, cellName(row, col), min)
+				}
+			}
+		}
+	}
+}
+
+func TestRemovalAmbiguity(t *testing.T) {
+	sol := mustGrid(t, testSolution)
+	min, first := sol.MinimizeReport(rand.New(rand.NewSource(1)))
+	if first == nil || min[first.Row][first.Col] != first.Value {
+		t.Fatalf("MinimizeReport first ambiguity %+v is not a clue of %s", first, min)
+	}
+	tests := []struct {
+		name     string
+		g        Grid
+		row, col int
+		ok       bool
+	}{
+		{"solution", sol, 4, 4, false},
+		{"empty cell", mustGrid(t, testPuzzle), 0, 2, false},
+		{"first kept clue", min, first.Row, first.Col, true},
+	}
+	for _, tt := range tests {
+		amb, ok := tt.g.RemovalAmbiguity(tt.row, tt.col)
+		if ok != tt.ok {
+			t.Errorf("%s: RemovalAmbiguity(%d, %d) = %+v, %v, want %v", tt.name, tt.row, tt.col, amb, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		// the alternate digit gives the puzzle without the clue a second solution
+		alt := tt.g
+		alt[tt.row][tt.col] = amb.Alternate
+		if amb.Value != tt.g[tt.row][tt.col] || amb.Alternate == amb.Value || alt.CountSolutions(1) != 1 {
+			t.Errorf("%s: ambiguity %+v", tt.name, amb)
+		}
+	}
+}
+
+func TestParseConstraints(t *testing.T) {
+	tests := []struct {
+		in      string
+		row     int
+		col     int
+		want    uint16
+		wantErr bool
+	}{
+		{"r4c2=13", 3, 1, 1<<1 | 1<<3, false},
+		{"R1C9=579; r4c2=1", 0, 8, 1<<5 | 1<<7 | 1<<9, false},
+		{"", 0, 0, 0, false},
+		{"r0c2=1", 0, 0, 0, true},
+		{"r4c2=0", 0, 0, 0, true},
+		{"row4=1", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseConstraints(%q) error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && c[tt.row][tt.col] != tt.want {
+			t.Errorf("ParseConstraints(%q) allows %b at %s, want %b", tt.in, c[tt.row][tt.col], cellName(tt.row, tt.col), tt.want)
+		}
+	}
+}
+
+func TestConstrainedSolutions(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		constraints string
+		want        int
+	}{
+		{"", 1},
+		{"r1c3=4", 1},
+		{"r1c3=12", 0},
+		{"r1c1=5", 1}, // a given its constraint allows
+		{"r1c1=6", 0}, // a given its constraint rules out
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraints(tt.constraints)
+		if err != nil {
+			t.Fatalf("ParseConstraints(%q): %v", tt.constraints, err)
+		}
+		if n := len(puzzle.ConstrainedSolutions(&c, 2)); n != tt.want {
+			t.Errorf("%q: %d solutions, want %d", tt.constraints, n, tt.want)
+		}
+	}
+}
+
+func TestRespectsGivens(t *testing.T) {
+	puzzle, solution := mustGrid(t, testPuzzle), mustGrid(t, testSolution)
+	overwritten := solution
+	overwritten[0][0] = 4
+	erased := solution
+	erased[0][0] = 0
+	tests := []struct {
+		name  string
+		board Grid
+		want  bool
+	}{
+		{"solution", solution, true},
+		{"puzzle itself", puzzle, true},
+		{"given overwritten", overwritten, false},
+		{"given erased", erased, false},
+		{"empty board", Grid{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.board.RespectsGivens(puzzle); got != tt.want {
+			t.Errorf("%s: RespectsGivens = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	g := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name string
+		got  [9]int
+		want [9]int
+	}{
+		{"first row", g.Row(0), [9]int{5, 3, 0, 0, 7, 0, 0, 0, 0}},
+		{"middle row", g.Row(4), [9]int{4, 0, 0, 8, 0, 3, 0, 0, 1}},
+		{"first column", g.Col(0), [9]int{5, 6, 0, 8, 4, 7, 0, 0, 0}},
+		{"last column", g.Col(8), [9]int{0, 0, 0, 3, 1, 6, 0, 5, 9}},
+		{"top left box", g.Box(0), [9]int{5, 3, 0, 6, 0, 0, 0, 9, 8}},
+		{"middle right box", g.Box(5), [9]int{0, 0, 3, 0, 0, 1, 0, 0, 6}},
+		{"bottom middle box", g.Box(7), [9]int{0, 0, 0, 4, 1, 9, 0, 8, 0}},
+		{"empty grid", Grid{}.Box(8), [9]int{}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestClueDistribution(t *testing.T) {
+	tests := []struct {
+		name                   string
+		g                      Grid
+		perRow, perCol, perBox [9]int
+	}{
+		{"empty", Grid{}, [9]int{}, [9]int{}, [9]int{}},
+		{"puzzle", mustGrid(t, testPuzzle),
+			[9]int{3, 4, 3, 3, 4, 3, 3, 4, 3},
+			[9]int{5, 3, 1, 3, 6, 3, 1, 3, 5},
+			[9]int{5, 4, 1, 3, 4, 3, 1, 4, 5}},
+		{"solution", mustGrid(t, testSolution),
+			[9]int{9, 9, 9, 9, 9, 9, 9, 9, 9},
+			[9]int{9, 9, 9, 9, 9, 9, 9, 9, 9},
+			[9]int{9, 9, 9, 9, 9, 9, 9, 9, 9}},
+	}
+	for _, tt := range tests {
+		perRow, perCol, perBox := tt.g.ClueDistribution()
+		if perRow != tt.perRow || perCol != tt.perCol || perBox != tt.perBox {
+			t.Errorf("%s: ClueDistribution = %v, %v, %v\nwant %v, %v, %v", tt.name, perRow, perCol, perBox, tt.perRow, tt.perCol, tt.perBox)
+		}
+	}
+}
+
+func TestSolutionsWithin(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name  string
+		g     Grid
+		limit int
+		depth int
+		sols  int
+		err   error
+	}{
+		{"puzzle", puzzle, 2, maxDepth, 1, nil},
+		{"puzzle at its empty cells", puzzle, 2, 51, 1, nil},
+		{"puzzle one short", puzzle, 2, 50, 0, errDepth},
+		{"solved with no depth", mustGrid(t, testSolution), 2, 0, 1, nil},
+		{"empty grid", Grid{}, 1, maxDepth, 1, nil},
+		{"empty grid one short", Grid{}, 1, maxDepth - 1, 0, errDepth},
+		{"empty grid shallow", Grid{}, 1, 10, 0, errDepth},
+	}
+	for _, tt := range tests {
+		sols, err := tt.g.SolutionsWithin(tt.limit, tt.depth)
+		if len(sols) != tt.sols || err != tt.err {
+			t.Errorf("%s: %d solutions, error %v, want %d, %v", tt.name, len(sols), err, tt.sols, tt.err)
+		}
+	}
+}
+
+func TestForcedCells(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	var empties [][2]int
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if puzzle[row][col] == 0 {
+				empties = append(empties, [2]int{row, col})
+			}
+		}
+	}
+	// the 6 and 7 of the rectangle can swap, the two holes cannot change
+	rectangle := mustGrid(t, testSolution)
+	rectangle[0][3], rectangle[0][4], rectangle[3][3], rectangle[3][4] = 0, 0, 0, 0
+	rectangle[4][4], rectangle[8][0] = 0, 0
+	tests := []struct {
+		name string
+		g    Grid
+		want [][2]int
+	}{
+		{"unique puzzle", puzzle, empties},
+		{"rectangle and holes", rectangle, [][2]int{{4, 4}, {8, 0}}},
+		{"solved", mustGrid(t, testSolution), [][2]int{}},
+		{"empty", Grid{}, [][2]int{}},
+		{"no solution", mustGrid(t, "123456780000000009"+strings.Repeat("0", 63)), nil},
+	}
+	for _, tt := range tests {
+		if got := tt.g.ForcedCells(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ForcedCells = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ Simple Sudoku .ss format.
+ Puzzles are nine rows of digits with . for empty cells, | between the
+ boxes of a row, and a line of dashes between the bands of boxes:
+
+	4..|...|8.5
+	.3.|...|...
+	...|7..|...
+	-----------
+	...
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	patternExportSS = "/api/export.ss" // puzzle in the .ss format
+	patternImportSS = "/api/import.ss" // .ss puzzle to the 81 character form
+)
+
+// ParseSS reads a puzzle in the .ss format.  Separator lines made of -, +,
+// and * are skipped, and | and spaces within a row are ignored.
+func ParseSS(r io.Reader) (Grid, error) {
+	var data []string
+	input := bufio.NewScanner(r)
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		if len(strings.Trim(line, "-+*| ")) == 0 {
+			continue
+		}
+		line = strings.NewReplacer("|", "", " ", "", "\t", "").Replace(line)
+		if len(line) != cols {
+			return Grid{}, fmt.Errorf("row %q does not have %d cells: %w", line, cols, errGridFormat)
+		}
+		data = append(data, line)
+	}
+	if err := input.Err(); err != nil {
+		return Grid{}, err
+	}
+	if len(data) != rows {
+		return Grid{}, fmt.Errorf("found %d rows, want %d: %w", len(data), rows, errGridFormat)
+	}
+	return ParseGrid(strings.Join(data, ""))
+}
+
+// WriteSS writes the grid in the .ss format
+func WriteSS(w io.Writer, g Grid) error {
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		if row > 0 && row%3 == 0 {
+			b.WriteString("-----------\n")
+		}
+		for col := 0; col < cols; col++ {
+			if col > 0 && col%3 == 0 {
+				b.WriteByte('|')
+			}
+			if g[row][col] == 0 {
+				b.WriteByte('.')
+			} else {
+				b.WriteByte(byte('0' + g[row][col]))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// handleExportSS returns the puzzle query parameter in the .ss format
+func handleExportSS(w http.ResponseWriter, r *http.Request) {
+	g, err := ParseGrid(r.URL.Query().Get("puzzle"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="puzzle.ss"`)
+	WriteSS(w, g)
+}
+
+// handleImportSS reads a posted .ss puzzle and returns it as 81 characters
+func handleImportSS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "method must be POST")
+		return
+	}
+	g, err := ParseSS(io.LimitReader(r.Body, maxImportSize))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Puzzle string `json:"puzzle"`
+	}{g.String()})
+}
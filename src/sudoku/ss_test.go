@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSS is testPuzzle in the .ss format
+const testSS = `53.|.7.|...
+6..|195|...
+.98|...|.6.
+-----------
+8..|.6.|..3
+4..|8.3|..1
+7..|.2.|..6
+-----------
+.6.|...|28.
+...|419|..5
+...|.8.|.79
+`
+
+func TestParseSS(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  error
+	}{
+		{"ss", testSS, nil},
+		{"spaces and other separators", strings.NewReplacer("|", " | ", "-----------", "---+---+---").Replace(testSS), nil},
+		{"no separators", strings.NewReplacer("|", "", "-----------\n", "").Replace(testSS), nil},
+		{"short row", strings.Replace(testSS, "53.|", "53|", 1), errGridFormat},
+		{"missing row", strings.Replace(testSS, "6..|195|...\n", "", 1), errGridFormat},
+		{"one line", testPuzzle, errGridFormat},
+	}
+	for _, tt := range tests {
+		g, err := ParseSS(strings.NewReader(tt.text))
+		if !errors.Is(err, tt.err) || err != nil && tt.err == nil {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && g.String() != testPuzzle {
+			t.Errorf("%s: ParseSS = %s, want %s", tt.name, g, testPuzzle)
+		}
+	}
+}
+
+func TestWriteSS(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+	}{
+		{"puzzle", mustGrid(t, testPuzzle)},
+		{"solution", mustGrid(t, testSolution)},
+		{"empty", Grid{}},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		if err := WriteSS(&b, tt.g); err != nil {
+			t.Fatalf("%s: WriteSS: %v", tt.name, err)
+		}
+		if tt.name == "puzzle" && b.String() != testSS {
+			t.Errorf("%s: WriteSS =\n%s\nwant\n%s", tt.name, b.String(), testSS)
+		}
+		if g, err := ParseSS(strings.NewReader(b.String())); err != nil || g != tt.g {
+			t.Errorf("%s: round trip gives %s, %v", tt.name, g, err)
+		}
+	}
+}
+
+func TestHandleSS(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleExportSS(rec, httptest.NewRequest(http.MethodGet, patternExportSS+"?puzzle="+testPuzzle, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != testSS || !strings.Contains(rec.Header().Get("Content-Disposition"), "puzzle.ss") {
+		t.Errorf("export: status code %d, body\n%s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+	}{
+		{"ss", http.MethodPost, testSS, http.StatusOK},
+		{"bad", http.MethodPost, "53.|.7.", http.StatusBadRequest},
+		{"too large", http.MethodPost, strings.Repeat("\n", maxImportSize) + testSS, http.StatusBadRequest},
+		{"get", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		var resp struct {
+			Puzzle string `json:"puzzle"`
+		}
+		code := callAPI(t, handleImportSS, tt.method, patternImportSS, tt.body, &resp)
+		if code != tt.code || code == http.StatusOK && resp.Puzzle != testPuzzle {
+			t.Errorf("%s: import status code %d with %q, want %d", tt.name, code, resp.Puzzle, tt.code)
+		}
+	}
+}
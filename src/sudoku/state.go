@@ -0,0 +1,105 @@
+/*
+ JSON form of the render state.
+ A SudokuT travels as its cells in reading order with their row and column
+ instead of the row_col_subgrid form field names, so clients can post the
+ whole state back for stateless features such as undo and redo.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// cellState is the JSON form of a Cell
+type cellState struct {
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Value   string `json:"value"`
+	Given   bool   `json:"given"`
+	Invalid bool   `json:"invalid"`
+	Hint    bool   `json:"hint,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// sudokuState is the JSON form of a SudokuT
+type sudokuState struct {
+	Cells       []cellState   `json:"cells"`
+	Constraints string        `json:"constraints"`
+	MoveLog     string        `json:"moveLog"`
+	BadCells    string        `json:"badCells,omitempty"`
+	Keypad      []KeypadDigit `json:"keypad,omitempty"`
+	NextEmpty   string        `json:"nextEmpty,omitempty"`
+	SolveToken  string        `json:"solveToken,omitempty"`
+	Status      Status        `json:"status"`
+}
+
+// MarshalJSON writes the cells of the grid in reading order
+func (s SudokuT) MarshalJSON() ([]byte, error) {
+	st := sudokuState{Cells: []cellState{}, Constraints: s.Constraints, MoveLog: s.MoveLog, BadCells: s.BadCells,
+		Keypad: s.Keypad, NextEmpty: s.NextEmpty, SolveToken: s.SolveToken, Status: s.Status}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			cell, ok := s.Grid[fmt.Sprintf("%d_%d_%d", row, col, subgrid)]
+			if !ok {
+				continue
+			}
+			st.Cells = append(st.Cells, cellState{
+				Row:     row,
+				Col:     col,
+				Value:   cell.Value,
+				Given:   cell.Readonly == "readonly",
+				Invalid: cell.Invalid == "invalid",
+				Hint:    cell.Hint,
+				Diff:    cell.Diff,
+			})
+		}
+	}
+	return json.Marshal(st)
+}
+
+// UnmarshalJSON rebuilds the grid from the cells written by MarshalJSON
+func (s *SudokuT) UnmarshalJSON(data []byte) error {
+	var st sudokuState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	grid := make(map[string]Cell)
+	for _, c := range st.Cells {
+		if !inBounds(c.Row, c.Col) {
+			return fmt.Errorf("cell %d,%d: %v", c.Row, c.Col, errOob)
+		}
+		subgrid := (c.Row/3)*3 + c.Col/3
+		name := fmt.Sprintf("%d_%d_%d", c.Row, c.Col, subgrid)
+		cell := Cell{Name: name, Value: c.Value, Invalid: "valid", Hint: c.Hint, Diff: c.Diff}
+		if c.Given {
+			cell.Name = name + "_ro"
+			cell.Readonly = "readonly"
+		}
+		if c.Invalid {
+			cell.Invalid = "invalid"
+		}
+		grid[name] = cell
+	}
+	*s = SudokuT{Grid: grid, Constraints: st.Constraints, MoveLog: st.MoveLog, BadCells: st.BadCells,
+		Keypad: st.Keypad, NextEmpty: st.NextEmpty, SolveToken: st.SolveToken, Status: st.Status}
+	return nil
+}
+
+// Givens returns the readonly cells of the grid with the player's entries left empty
+func (s SudokuT) Givens() Grid {
+	var g Grid
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			cell := s.Grid[fmt.Sprintf("%d_%d_%d", row, col, subgrid)]
+			if d, err := strconv.Atoi(cell.Value); err == nil && cell.Readonly == "readonly" && validDigit(d) {
+				g[row][col] = d
+			}
+		}
+	}
+	return g
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	board := puzzle
+	board[0][2] = 4
+	evaluated := newPuzzle(puzzle, board)
+	evaluated.MoveLog = "0,2,0,4,1000"
+	for d := 1; d <= 9; d++ {
+		evaluated.Keypad = append(evaluated.Keypad, KeypadDigit{Digit: d, Done: d == 4})
+	}
+	evaluated.NextEmpty = "0_3_1"
+	evaluated.SolveToken = "8f3a"
+	evaluated.Status = Status{Message: "Status: Valid Puzzle", State: "validstatus"}
+
+	marked := newPuzzle(puzzle, board)
+	marked.Grid["0_2_0"] = Cell{Name: "0_2_0", Value: "4", Invalid: "invalid", Hint: true, Diff: "mistake"}
+	marked.Constraints = "0,2:14"
+	marked.BadCells = "0_2_0"
+	marked.Status = Status{Message: "Status: Invalid", State: "invalidstatus"}
+
+	tests := []struct {
+		name string
+		s    SudokuT
+	}{
+		{"puzzle", newPuzzle(puzzle, puzzle)},
+		{"evaluated", evaluated},
+		{"marked", marked},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.s)
+		if err != nil {
+			t.Fatalf("%s: Marshal: %v", tt.name, err)
+		}
+		var got SudokuT
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: Unmarshal: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.s) {
+			t.Errorf("%s: round trip\n got %+v\nwant %+v", tt.name, got, tt.s)
+		}
+	}
+}
+
+func TestStateUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		cells   int
+		wantErr bool
+	}{
+		{"no cells", `{"cells":[]}`, 0, false},
+		{"one given", `{"cells":[{"row":8,"col":8,"value":"9","given":true}]}`, 1, false},
+		{"out of bounds", `{"cells":[{"row":9,"col":0,"value":"1"}]}`, 0, true},
+		{"negative", `{"cells":[{"row":0,"col":-1,"value":"1"}]}`, 0, true},
+		{"not json", `cells`, 0, true},
+	}
+	for _, tt := range tests {
+		var s SudokuT
+		err := json.Unmarshal([]byte(tt.data), &s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && len(s.Grid) != tt.cells {
+			t.Errorf("%s: %d cells, want %d", tt.name, len(s.Grid), tt.cells)
+		}
+	}
+	var s SudokuT
+	if err := json.Unmarshal([]byte(`{"cells":[{"row":8,"col":8,"value":"9","given":true}]}`), &s); err == nil {
+		if c := s.Grid["8_8_8"]; c.Name != "8_8_8_ro" || c.Readonly != "readonly" || c.Invalid != "valid" {
+			t.Errorf("given cell %+v", c)
+		}
+	}
+}
+
+func TestGivens(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	board := mustGrid(t, testSolution)
+	tampered := newPuzzle(puzzle, puzzle)
+	tampered.Grid["0_0_0"] = Cell{Name: "0_0_0_ro", Value: "10", Invalid: "valid", Readonly: "readonly"}
+	tampered.Grid["0_1_0"] = Cell{Name: "0_1_0_ro", Value: "x", Invalid: "valid", Readonly: "readonly"}
+	withoutCorner := puzzle
+	withoutCorner[0][0], withoutCorner[0][1] = 0, 0
+	tests := []struct {
+		name string
+		s    SudokuT
+		want Grid
+	}{
+		{"puzzle", newPuzzle(puzzle, puzzle), puzzle},
+		{"entries left out", newPuzzle(puzzle, board), puzzle},
+		{"no givens", newPuzzle(Grid{}, board), Grid{}},
+		{"no cells", SudokuT{}, Grid{}},
+		{"tampered givens", tampered, withoutCorner},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Givens(); got != tt.want {
+			t.Errorf("%s: Givens = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
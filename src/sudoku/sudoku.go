@@ -17,8 +17,10 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"embed"
 	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"log"
@@ -34,20 +36,24 @@ const (
 	subgrids      int = 9
 	rows          int = 9
 	cols          int = 9
-	tmpl              = "../../src/sudoku/templates/sudoku.html" // html template relative address
-	addr              = "127.0.0.1:8080"                         // http server listen address
-	pattern           = "/sudoku"                                // http handler initialization pattern
-	patternSubmit     = "/sudoku-submit"                         // http handler submit pattern
-	initGridFile      = "../../src/sudoku/grids/sudoku50.txt"
+	tmpl              = "templates/sudoku.html" // html template in assets
+	addr              = "127.0.0.1:8080"        // http server listen address
+	pattern           = "/sudoku"               // http handler initialization pattern
+	patternSubmit     = "/sudoku-submit"        // http handler submit pattern
+	patternStatic     = "/static/"              // http handler static assets pattern
+	initGridFile      = "grids/sudoku50.txt"    // initial puzzle in assets
+	gridDir           = "grids"                 // bundled grid files in assets
 	nTrials           = 1000
 )
 
 // Each cell in the grid has these properties.
 type Cell struct {
-	Name     string // row_col_subgrd, row=[0-8], col=[0-8], subgrd=[0-8]
-	Value    string // [1-9]
-	Invalid  string // invalid or valid user cell value doesn't obey rules
-	Readonly string // readonly; given initial grid entries cannot be changed
+	Name     string `json:"name"`           // row_col_subgrd, row=[0-8], col=[0-8], subgrd=[0-8]
+	Value    string `json:"value"`          // [1-9]
+	Invalid  string `json:"invalid"`        // invalid or valid user cell value doesn't obey rules
+	Readonly string `json:"readonly"`       // readonly; given initial grid entries cannot be changed
+	Hint     bool   `json:"hint,omitempty"` // highlight the cell as the target of a hint
+	Diff     string `json:"diff,omitempty"` // correct, solved, or mistake after solving from the givens
 }
 
 // Sudoku board is a 9x9 grid (81 squares) consisting of nine 3x3 (9 squares) subregions.
@@ -56,8 +62,8 @@ type Grid [rows][cols]int
 
 // Bad cell
 type Bad struct {
-	rule string // row, col, subgrid rule violated
-	num  int    // 1-9 of the rule
+	rule string // row, col, subgrid rule violated, the Kind of a Unit
+	num  int    // 0-8 of the rule, the Num of a Unit
 	val  string // "1" - "9"
 }
 
@@ -72,11 +78,27 @@ type result struct {
 type SudokuError []error
 
 type SudokuT struct {
-	Grid   map[string]Cell // Sudoku grid
-	Status struct {        // status of the puzzle
-		Message string // Puzzle state
-		State   string //  validstatus, invalidstatus, solvedstatus
-	}
+	Grid        map[string]Cell // Sudoku grid
+	Constraints string          // user constraints for the solve option, r4c2=13
+	MoveLog     string          // moves made so far, see MoveLog
+	BadCells    string          // cells holding entries other than 1-9 at the last evaluate
+	Keypad      []KeypadDigit   // digits 1-9 for the keypad, set by evaluate
+	NextEmpty   string          // name of the first empty cell in reading order, set by evaluate
+	SolveToken  string          // confirms a solve that replaces entries breaking the rules
+	Status      Status          // status of the puzzle
+}
+
+// KeypadDigit is a key of the keypad, Done when the digit is placed
+// nine times with no conflicts so the key can be grayed out
+type KeypadDigit struct {
+	Digit int  `json:"digit"`
+	Done  bool `json:"done"`
+}
+
+// Status is the message shown under the grid
+type Status struct {
+	Message string `json:"message"` // Puzzle state
+	State   string `json:"state"`   //  validstatus, invalidstatus, solvedstatus
 }
 
 var (
@@ -88,12 +110,38 @@ var (
 )
 
 var (
-	t *template.Template
+	t      *template.Template
+	tprint *template.Template // puzzle and solution for printing
 )
 
-// init parses the html template file done only once
+// command line options
+var (
+	strict      = flag.Bool("strict", false, "reject an evaluate submission holding entries other than 1-9")
+	workers     = flag.Int("workers", subgrids, "goroutines finding subregion results, 1 for deterministic debugging")
+	checkGrade  = flag.Bool("checkgrade", false, "regrade every generated puzzle and log a warning when it misses its difficulty")
+	selfTestRun = flag.Bool("selftest", false, "check every grid file before serving and exit with status 1 if any is broken")
+	gridFiles   = flag.String("griddir", "", "directory of the grid files checked by -selftest, the bundled ones when empty")
+	badEntries  = flag.String("badentries", "keep", "keep entries other than 1-9 marked invalid at the next evaluate, or clear them")
+	corsOrigins = flag.String("cors", "", "comma separated origins allowed to call the JSON API, * for any, empty for same origin only")
+	claimSecret = flag.String("claimsecret", "", "key signing the tokens of /api/claim, random on every start when empty")
+)
+
+// static holds the CSS assets compiled into the binary and served under patternStatic
+//
+//go:embed css
+var static embed.FS
+
+// assets holds the templates and grid files compiled into the binary, so
+// the server does not depend on its working directory
+//
+//go:embed templates grids grids12
+var assets embed.FS
+
+// init parses the html template files done only once
 func init() {
-	t = template.Must(template.ParseFiles(tmpl))
+	t = template.Must(template.ParseFS(assets, tmpl))
+	tprint = template.Must(template.ParseFS(assets, printTmpl))
+	t12 = template.Must(template.ParseFS(assets, tmpl12))
 }
 
 // Error returns one or more errors separated by commas
@@ -108,40 +156,21 @@ func (se SudokuError) Error() string {
 // handleSudoku processes the initial Sudoku connection
 func handleSudoku(w http.ResponseWriter, r *http.Request) {
 	// Open file
-	f, err := os.Open(initGridFile)
+	f, err := assets.Open(initGridFile)
 	if err != nil {
 		log.Fatalf("Error opening %s: %v\n", initGridFile, err)
 	}
 	defer f.Close()
 
-	var sudoku SudokuT
-	sudoku.Grid = make(map[string]Cell)
-
-	// Fill in the grid
-	input := bufio.NewScanner(f)
-	row := 0
-	for input.Scan() {
-		line := input.Text()
-		// Each line has 9 values:  numbers 1-9
-		values := strings.Split(line, " ")
-		col := 0
-		for _, val := range values {
-			subgrid := (row/3)*3 + col/3
-			name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
-			// Mark as readonly in name by appending "_ro"
-			if val != "0" {
-				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
-			} else {
-				sudoku.Grid[name] = Cell{Name: name, Value: "", Invalid: "valid", Readonly: ""}
-			}
-			col++
-		}
-		row++
+	// Fill in the grid, skipping blank and comment lines
+	g, err := ReadPuzzle(f)
+	if err != nil {
+		log.Printf("Error reading %s: %v\n", initGridFile, err)
+		http.Error(w, "Bad puzzle file "+initGridFile, http.StatusInternalServerError)
+		return
 	}
-
-	// Set puzzle status
-	sudoku.Status.Message = "Status: Valid Puzzle"
-	sudoku.Status.State = "validstatus"
+	sudoku := newPuzzle(g, g)
+	setPuzzleHeaders(w, g)
 
 	// Write to HTTP output using template and grid
 	if err = t.Execute(w, sudoku); err != nil {
@@ -149,25 +178,83 @@ func handleSudoku(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// notFound is the page for paths that match no handler
+var notFound = template.Must(template.New("notfound").Parse(`<!DOCTYPE html>
+<html lang="eng">
+	<head>
+		<title>Page not found</title>
+		<meta charset="utf-8" />
+	</head>
+	<body>
+		<p>There is no page at {{.}}.</p>
+		<p>The puzzle is at <a href="` + pattern + `">` + pattern + `</a>,
+		and the JSON API endpoints are under ` + patternAPI + `.</p>
+	</body>
+</html>
+`))
+
+// handleRoot redirects the base URL to the puzzle and answers every other
+// unmatched path with a 404 pointing to the valid routes
+func handleRoot(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		http.Redirect(w, r, pattern, http.StatusFound)
+	case strings.HasPrefix(r.URL.Path, patternAPI):
+		writeJSONError(w, http.StatusNotFound, "no API endpoint at "+r.URL.Path)
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusNotFound)
+		if err := notFound.Execute(w, r.URL.Path); err != nil {
+			log.Printf("Write not found page error: %v\n", err)
+		}
+	}
+}
+
+// conflictSummary reports the number of row, column, and subgrid conflicts
+// shown after "Status: Invalid, ".  Duplicate Bad entries for the same
+// rule, unit, and value count once.
+func conflictSummary(invalids []Bad, badValues int) string {
+	seen := make(map[Bad]bool)
+	counts := make(map[string]int)
+	for _, bad := range invalids {
+		if !seen[bad] {
+			seen[bad] = true
+			counts[bad.rule]++
+		}
+	}
+	msg := fmt.Sprintf("%d row, %d column, %d box conflicts",
+		counts["row"], counts["col"], counts["subgrid"])
+	if badValues > 0 {
+		msg += fmt.Sprintf(", %d bad values", badValues)
+	}
+	return msg
+}
+
 // handleSudokuSubmit processes the Sudoku form submission for evaluate option
 func evaluateSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 
-	// histograms for row, column, and subgrid values holding counts
-	// for values 1-9.  invalids hold the bad cell information
+	// values holds the digits 1-9 of the grid and raw the text entered for
+	// them, invalids hold the bad cell information
 	// grid is the Sudoku grid showing the values
 	var (
-		colHist    [cols][10]int8
-		rowHist    [rows][10]int8
-		sgHist     [subgrids][10]int8
+		values     [rows][cols]int
+		raw        [rows][cols]string
 		invalids   []Bad
-		emptyCells int = 0
-		badValues  int = 0
+		emptyCells int    = 0
+		badValues  int    = 0
+		firstBad   string // first entry that is not a digit 1-9
+		badCells   []string
 		sudoku     SudokuT
 	)
 	sudoku.Grid = make(map[string]Cell)
 
+	// cells that held an entry other than 1-9 at the last evaluate
+	prevBad := make(map[string]bool)
+	for _, name := range strings.Split(r.FormValue("badcells"), ",") {
+		prevBad[name] = true
+	}
+
 	// Loop over the rows/columns, get the Request form values, insert into the grid
-	// Verify values obey Sudoku rules.
 	for row := 0; row < rows; row++ {
 		for col := 0; col < cols; col++ {
 			subgrid := (row/3)*3 + col/3
@@ -175,72 +262,116 @@ func evaluateSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 			// Check for readonly cell first by appending "_ro"
 			val := r.FormValue(name + "_ro")
 			if len(val) > 0 {
-				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
-				n, _ := strconv.Atoi(val)
-				colHist[col][n]++
-				// Mark bad if column rule violated
-				if colHist[col][n] > 1 {
-					invalids = append(invalids, Bad{rule: "col", num: col, val: val})
-				}
-				rowHist[row][n]++
-				// Mark bad if row rule violated
-				if rowHist[row][n] > 1 {
-					invalids = append(invalids, Bad{rule: "row", num: row, val: val})
-				}
-				sgHist[subgrid][n]++
-				// Mark bad if subgrid rule violated
-				if sgHist[subgrid][n] > 1 {
-					invalids = append(invalids, Bad{rule: "subgrid", num: subgrid, val: val})
+				// A given outside 1-9 can only come from a tampered form
+				d, err := strconv.Atoi(val)
+				if err != nil || !validDigit(d) {
+					http.Error(w, fmt.Sprintf("Bad form submission: given %s is %q: %v", cellName(row, col), val, errInvalDig),
+						http.StatusBadRequest)
+					return
 				}
+				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
+				values[row][col] = d
+				raw[row][col] = val
 			} else {
 				val = r.FormValue(name)
 				// check for valid entry that is not empty ""
 				if len(val) > 0 {
-					if n, err := strconv.Atoi(val); err == nil {
-						if n > 0 && n < 10 {
-							colHist[col][n]++
-							// Mark bad if column rule violated
-							if colHist[col][n] > 1 {
-								invalids = append(invalids, Bad{rule: "col", num: col, val: val})
-							}
-							rowHist[row][n]++
-							// Mark bad if row rule violated
-							if rowHist[row][n] > 1 {
-								invalids = append(invalids, Bad{rule: "row", num: row, val: val})
-							}
-							sgHist[subgrid][n]++
-							// Mark bad if subgrid rule violated
-							if sgHist[subgrid][n] > 1 {
-								invalids = append(invalids, Bad{rule: "subgrid", num: subgrid, val: val})
-							}
-
-							// Insert Cell state into the grid for valid
-							sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
-						} else {
-							// Mark bad
-							sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "invalid", Readonly: ""}
-							badValues++
-
+					if n, err := strconv.Atoi(val); err == nil && n > 0 && n < 10 {
+						values[row][col] = n
+						raw[row][col] = val
+
+						// Insert Cell state into the grid for valid
+						sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
+					} else if *badEntries == "clear" && prevBad[name] {
+						// Clear a bad value already reported at the last evaluate
+						sudoku.Grid[name] = Cell{Name: name, Value: "", Invalid: "valid", Readonly: ""}
+						emptyCells++
+						if sudoku.NextEmpty == "" {
+							sudoku.NextEmpty = name
 						}
 					} else {
 						// Mark bad
 						sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "invalid", Readonly: ""}
 						badValues++
+						badCells = append(badCells, name)
+						if firstBad == "" {
+							firstBad = fmt.Sprintf("%s holds %q", cellName(row, col), val)
+						}
 					}
 				} else {
 					sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
 					emptyCells++
+					if sudoku.NextEmpty == "" {
+						sudoku.NextEmpty = name
+					}
 				}
 			}
 		}
 	}
 
-	// Set puzzle status
-	if len(invalids) > 0 || badValues > 0 {
-		sudoku.Status.Message = "Status: Invalid Puzzle"
+	// Verify values obey Sudoku rules, a histogram per unit holds the
+	// counts for values 1-9.  Each cell contributes at most once, as
+	// FormValue reads only the first of repeated fields for a cell, and
+	// counts stop at 2 since only duplicates matter, so a unit of any
+	// size cannot overflow the int8 counters.
+	for _, u := range rules.Units() {
+		var hist [10]int8
+		for _, rc := range u.Cells {
+			n := values[rc[0]][rc[1]]
+			if n < 1 || n > 9 {
+				continue
+			}
+			if hist[n] < 2 {
+				hist[n]++
+			}
+			// Mark bad if the unit rule violated
+			if hist[n] > 1 {
+				invalids = append(invalids, Bad{rule: u.Kind, num: u.Num, val: raw[rc[0]][rc[1]]})
+			}
+		}
+	}
+
+	// A digit is done when all nine are placed and none is in a conflict
+	var conflicted [10]bool
+	for _, bad := range invalids {
+		if n, err := strconv.Atoi(bad.val); err == nil && n > 0 && n < 10 {
+			conflicted[n] = true
+		}
+	}
+	counts := Grid(values).DigitCounts()
+	for d := 1; d <= 9; d++ {
+		sudoku.Keypad = append(sudoku.Keypad, KeypadDigit{Digit: d, Done: counts[d] == 9 && !conflicted[d]})
+	}
+
+	sudoku.BadCells = strings.Join(badCells, ",")
+
+	// Record the cells changed since the last submission in the move log
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	moves, err := ParseMoveLog(r.FormValue("movelog"), givens)
+	if err == nil {
+		moves = moves.Record(givens, board, time.Now())
+		sudoku.MoveLog = moves.String()
+	} else {
+		log.Printf("Move log error: %v\n", err)
+	}
+
+	// Set puzzle status, strict mode rejects the submission outright for a bad entry
+	if *strict && firstBad != "" {
+		sudoku.Status.Message = "Status: Rejected, " + firstBad + ", enter only digits 1-9"
+		sudoku.Status.State = "invalidstatus"
+		w.WriteHeader(http.StatusBadRequest)
+	} else if len(invalids) > 0 || badValues > 0 {
+		sudoku.Status.Message = "Status: Invalid, " + conflictSummary(invalids, badValues)
 		sudoku.Status.State = "invalidstatus"
 	} else if emptyCells == 0 {
 		sudoku.Status.Message = "Status: Solved Puzzle"
+		if rating, ok := moves.SpeedRating(givens); ok {
+			sudoku.Status.Message += ", " + rating
+		}
 		sudoku.Status.State = "solvedstatus"
 	} else {
 		sudoku.Status.Message = "Status: Valid Puzzle"
@@ -249,43 +380,20 @@ func evaluateSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 
 	// Process invalid values and mark the cells invalid for non-readonly cells
 	for _, bad := range invalids {
-		if bad.rule == "row" {
-			// Scan the columns of this row and mark any non-readonly invalid cells
-			for col := 0; col < 9; col++ {
-				subgrid := (bad.num/3)*3 + col/3
-				name := fmt.Sprintf("%d_%d_%d", bad.num, col, subgrid)
-				if sudoku.Grid[name].Value == bad.val && sudoku.Grid[name].Readonly == "" {
-					cell := sudoku.Grid[name]
-					cell.Invalid = "invalid"
-					sudoku.Grid[name] = cell
-				}
+		for _, u := range rules.Units() {
+			if u.Kind != bad.rule || u.Num != bad.num {
+				continue
 			}
-		} else if bad.rule == "col" {
-			// Scan the rows of this column and mark any invalid cells
-			for row := 0; row < 9; row++ {
-				subgrid := (row/3)*3 + bad.num/3
-				name := fmt.Sprintf("%d_%d_%d", row, bad.num, subgrid)
+			// Scan the cells of this unit and mark any non-readonly invalid cells
+			for _, rc := range u.Cells {
+				subgrid := (rc[0]/3)*3 + rc[1]/3
+				name := fmt.Sprintf("%d_%d_%d", rc[0], rc[1], subgrid)
 				if sudoku.Grid[name].Value == bad.val && sudoku.Grid[name].Readonly == "" {
 					cell := sudoku.Grid[name]
 					cell.Invalid = "invalid"
 					sudoku.Grid[name] = cell
 				}
 			}
-		} else { // subgrid
-			// Scan the rows and columns of this subgrid and mark any invalid cells.
-			// Do not mark Readonly cells.
-			r0 := (bad.num / 3) * 3
-			c0 := (bad.num % 3) * 3
-			for row := r0; row < r0+3; row++ {
-				for col := c0; col < c0+3; col++ {
-					name := fmt.Sprintf("%d_%d_%d", row, col, bad.num)
-					if sudoku.Grid[name].Value == bad.val && sudoku.Grid[name].Readonly == "" {
-						cell := sudoku.Grid[name]
-						cell.Invalid = "invalid"
-						sudoku.Grid[name] = cell
-					}
-				}
-			}
 		}
 	}
 
@@ -326,50 +434,40 @@ func resetSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// newSudokuSubmit processes the Sudoku form submission for new option
-func newSudokuSubmit(w http.ResponseWriter, r *http.Request) {
-
-	var (
-		n      int
-		err    error
-		s      Grid // Grid to use in solver functions
-		sudoku SudokuT
-	)
-	sudoku.Grid = make(map[string]Cell)
-
-	// Get the number of blank cells
-	fv := r.FormValue("blankvalues")
-	if len(fv) > 0 {
-		if n, err = strconv.Atoi(fv); err != nil {
-			log.Fatalf("Blank value conversion error: %v\n", err)
-		}
-	} else {
-		log.Fatal("No blank cells specified in dropdown list.")
-	}
-
-	// seed the random number generator
-	rand.Seed(time.Now().Unix())
-
-	// trials or attempts to solve the Sudoku puzzle
-	trial := 0
+// trialSolve fills the empty cells of the grid by randomized trials.  Each
+// trial repeatedly gives a random candidate to the cell with the fewest
+// candidates and restarts from the starting grid when a cell has none.
+// It returns the trials used, the time spent finding the candidates of the
+// subregions, the time spent assigning candidates and restarting trials,
+// and whether the grid was solved within nTrials before ctx ended.
+// An unsolved grid is left as it started.
+func (g *Grid) trialSolve(ctx context.Context, rng *rand.Rand) (trial int, propagation, backtracking time.Duration, solved bool) {
+	start := *g
 	results := make(chan result)
 	begin := time.Now()
 	fmt.Printf("\nStart time: %v\n", begin.Format(time.StampMilli))
+	defer func() {
+		fmt.Printf("\nEnd time: %v, run time: %v\n", time.Now().Format(time.StampMilli), time.Since(begin))
+	}()
 trials:
 	for trial < nTrials {
 		trial++
 		fmt.Printf("Trial %v\n", trial)
 		nsets := 0
 		// loop for nsets
-	sets:
 		for {
-			// launch a goroutine for each 3x3 subregion to find results
-			for r := 0; r < rows; r += rows / 3 {
-				for c := 0; c < cols; c += cols / 3 {
-					go s.getResult(int(r), int(c), results)
-				}
+			// stop when the client has gone away
+			select {
+			case <-ctx.Done():
+				*g = start
+				return trial, propagation, backtracking, false
+			default:
 			}
 
+			scan := time.Now()
+			// launch the workers to find results for the 3x3 subregions
+			g.subregionResults(results)
+
 			nchoices := 10 // how many digits available for this cell in a sub-region
 			var cell result
 			noneAssigned := 0 // number of subregions that are completely assigned values
@@ -378,44 +476,124 @@ trials:
 				r := <-results
 				if r.notAssigned == 0 {
 					noneAssigned++
-				} else if r.nchoices < nchoices {
+				} else if r.nchoices < nchoices || r.nchoices == nchoices && r.before(cell) {
 					nchoices = r.nchoices
 					cell = r
 				}
 			}
+			propagation += time.Since(scan)
 
 			// puzzle solved if all cells filled with valid values
 			if noneAssigned == rows {
-				// Show the Sudoku board that is the solution
 				fmt.Printf("\n                Solved Sudoku                    \n")
-				break trials
+				return trial, propagation, backtracking, true
 			}
 
 			// no solution if nchoices is zero in any subregion with unassigned cells
 			// start a new trial
+			choose := time.Now()
 			if nchoices == 0 {
-				NewSudoku(r, &sudoku, &s)
+				*g = start
+				backtracking += time.Since(choose)
 				fmt.Printf("Number of sets done for trial %v is %v. Start new trial.\n",
 					trial, nsets)
-				break sets
+				continue trials
 			}
 
 			// Assign a random value for the cell and continue this trial
-			n := rand.Intn(nchoices)
-			s.Set(cell.y, cell.x, cell.choices[n])
+			n := rng.Intn(nchoices)
+			g.Set(cell.y, cell.x, cell.choices[n])
+			backtracking += time.Since(choose)
 			nsets++
 		}
 	}
-	fmt.Printf("\nEnd time: %v, run time: %v\n", time.Now().Format(time.StampMilli), time.Since(begin))
+	*g = start
+	return trial, propagation, backtracking, false
+}
+
+// completeBoardSubmit answers a solve submission of a board with no empty
+// cells by checking it instead of solving it.  New always generates a
+// fresh puzzle, so only solve calls it.
+// It returns false, writing nothing, when the board has empty cells.
+func completeBoardSubmit(w http.ResponseWriter, r *http.Request) bool {
+	givens, board, err := readBoard(r)
+	if err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return true
+	}
+	if board.Clues() < rows*cols {
+		return false
+	}
+	sudoku := newPuzzle(givens, board)
+	sudoku.MoveLog = r.FormValue("movelog")
+	if board.GivensConsistent() {
+		sudoku.Status.Message = "Status: Solved Puzzle, the board is already complete"
+		sudoku.Status.State = "solvedstatus"
+	} else {
+		sudoku.Status.Message = "Status: Invalid, the board is complete but breaks the rules"
+		sudoku.Status.State = "invalidstatus"
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+	return true
+}
+
+// newSudokuSubmit processes the Sudoku form submission for new option
+func newSudokuSubmit(w http.ResponseWriter, r *http.Request) {
+
+	var (
+		n      int
+		err    error
+		s      Grid // Grid to use in solver functions
+		sudoku SudokuT
+	)
+	sudoku.Grid = make(map[string]Cell)
+
+	// Get the number of blank cells
+	fv := r.FormValue("blankvalues")
+	if len(fv) > 0 {
+		if n, err = strconv.Atoi(fv); err != nil {
+			log.Fatalf("Blank value conversion error: %v\n", err)
+		}
+	} else {
+		log.Fatal("No blank cells specified in dropdown list.")
+	}
+
+	// random number generator for the choices, all randomness comes from it,
+	// so the same seed and blank cells generate the same puzzle
+	seed := time.Now().UnixNano()
+	if fv := r.FormValue("seed"); len(fv) > 0 {
+		if seed, err = strconv.ParseInt(strings.TrimSpace(fv), 10, 64); err != nil {
+			http.Error(w, "Bad form submission: seed must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	// fill an empty grid, stopping when the client has gone away
+	begin := time.Now()
+	trial, _, _, ok := s.trialSolve(r.Context(), rng)
+	if r.Context().Err() != nil {
+		fmt.Printf("\nGeneration cancelled after %v trials: %v\n", trial, r.Context().Err())
+		return
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("No puzzle generated in %d trials", trial), http.StatusInternalServerError)
+		return
+	}
+	recordGeneration(fmt.Sprintf("%d blanks", n), trial, time.Since(begin))
 
 	// Add nflag zeros in random positions to the Grid
 	for i := 0; i < n; i++ {
-		r := rand.Intn(rows)
-		c := rand.Intn(cols)
+		r := rng.Intn(rows)
+		c := rng.Intn(cols)
 		// check if already set to zero and try r,c another if so
 		for s[r][c] == 0 {
-			r = rand.Intn(rows)
-			c = rand.Intn(cols)
+			r = rng.Intn(rows)
+			c = rng.Intn(cols)
 		}
 		s[r][c] = 0
 	}
@@ -436,8 +614,10 @@ trials:
 		}
 	}
 
-	// Set puzzle status
-	sudoku.Status.Message = "Status: Valid Puzzle"
+	setPuzzleHeaders(w, s)
+
+	// Set puzzle status with the seed to share the puzzle
+	sudoku.Status.Message = fmt.Sprintf("Status: Valid Puzzle, seed %d", seed)
 	sudoku.Status.State = "validstatus"
 
 	// Write to HTTP output using template and grid
@@ -446,77 +626,92 @@ trials:
 	}
 }
 
-// getResult finds cells in subregion not set and their satisfying values
-func (g *Grid) getResult(r, c int, out chan<- result) {
-	var setsSR [10]int
-	// count values in this subregion, setsSR
-	for i := r; i < r+3; i++ {
-		for j := c; j < c+3; j++ {
-			setsSR[g[i][j]]++
+// unitCounts holds how many times each value 0-9 occurs in every unit of
+// the grid, indexed like standardUnits
+type unitCounts struct {
+	unit [rows + cols + subgrids][10]uint8
+}
+
+// countUnits counts the values of every row, column, and subregion in one pass
+func (g *Grid) countUnits() *unitCounts {
+	var uc unitCounts
+	for i, u := range standardUnits {
+		for _, rc := range u.Cells {
+			uc.unit[i][g[rc[0]][rc[1]]]++
 		}
 	}
+	return &uc
+}
+
+// before orders results by cell position so ties between subregions with the
+// same number of choices do not depend on which goroutine answered first
+func (res result) before(other result) bool {
+	return res.y < other.y || res.y == other.y && res.x < other.x
+}
+
+// subregionResults counts the values in every unit once, then splits the
+// nine 3x3 subregions among the workers, each sending one result per subregion
+func (g *Grid) subregionResults(out chan<- result) {
+	counts := g.countUnits()
+	n := *workers
+	if n < 1 {
+		n = 1
+	} else if n > subgrids {
+		n = subgrids
+	}
+	for w := 0; w < n; w++ {
+		go func(w int) {
+			for sg := w; sg < subgrids; sg += n {
+				g.getResult(sg, counts, out)
+			}
+		}(w)
+	}
+}
+
+// getResult finds cells in subregion sg not set and their satisfying values.
+// The unit counts are shared by all subregions and must not be modified.
+func (g *Grid) getResult(sg int, uc *unitCounts, out chan<- result) {
+	box := rows + cols + sg
+	cells := standardUnits[box].Cells
+	// counts of values in this subregion
+	setsSR := &uc.unit[box]
 	// check if all values are set implies no cells have value zero
 	if setsSR[0] == 0 {
-		out <- result{notAssigned: 0, x: c, y: r, nchoices: 0, choices: nil}
+		out <- result{notAssigned: 0, x: cells[0][1], y: cells[0][0], nchoices: 0, choices: nil}
 		return
 	}
 
-	// count values of the columns of this subregion, setsCL
-	var setsCL [3][10]int
-	for cc := c; cc < c+3; cc++ {
-		for i := 0; i < rows; i++ {
-			setsCL[cc-c][g[i][cc]]++
-		}
-	}
-
-	// count values of the rows of this subregion, setsRW
-	var setsRW [3][10]int
-	for rr := r; rr < r+3; rr++ {
-		for j := 0; j < cols; j++ {
-			setsRW[rr-r][g[rr][j]]++
+	// unused returns the values 1 to 9 that no unit of the cell holds
+	unused := func(rr, cc int) []int {
+		var vals []int
+		for i := 1; i < 10; i++ {
+			var sets uint8
+			for _, u := range standardTable.cellUnits[rr][cc] {
+				sets += uc.unit[u][i]
+			}
+			if sets == 0 {
+				vals = append(vals, i)
+			}
 		}
+		return vals
 	}
 
-	// check every cell in this 3x3 subregion for non-assignment
-	var (
-		xc int
-		yr int
-	)
-	min := 10
-	cnt := 0
-	for rr := r; rr < r+3; rr++ {
-		for cc := c; cc < c+3; cc++ {
-			if g[rr][cc] == 0 {
-				// check counts for values 1 to 9
-				for i := 1; i < 10; i++ {
-					sets := setsSR[i] + setsCL[cc-c][i] + setsRW[rr-r][i]
-					if sets == 0 {
-						cnt++
-					}
-				}
-				if cnt < min {
-					xc = cc
-					yr = rr
-					min = cnt
-				}
-				cnt = 0
+	// check every cell in this subregion for non-assignment
+	var choices []int
+	xc, yr := -1, -1
+	for _, rc := range cells {
+		if g[rc[0]][rc[1]] == 0 {
+			if vals := unused(rc[0], rc[1]); xc < 0 || len(vals) < len(choices) {
+				xc, yr, choices = rc[1], rc[0], vals
 			}
 		}
 	}
 
 	// create result to send to out channel
-	unused := make([]int, min)
-	j := 0
-	// check counts for values 1 to 9 as before
-	for i := 1; i < 10; i++ {
-		n := setsSR[i] + setsCL[xc-c][i] + setsRW[yr-r][i]
-		if n == 0 {
-			unused[j] = int(i)
-			j++
-		}
+	if choices == nil {
+		choices = []int{}
 	}
-	res := result{notAssigned: setsSR[0], x: xc, y: yr, choices: unused, nchoices: min}
-	out <- res
+	out <- result{notAssigned: int(setsSR[0]), x: xc, y: yr, choices: choices, nchoices: len(choices)}
 }
 
 // inBounds checks row,column are inside the grid
@@ -537,35 +732,7 @@ func validDigit(digit int) bool {
 
 // ruleCheck enforces the Sudoku rules for digit uniqueness in rows, columns, and subregions
 func (g *Grid) ruleCheck(row, col int, digit int) bool {
-	// row digit uniqueness constraint
-	for c := 0; c < cols; c++ {
-		if g[row][c] == digit {
-			fmt.Printf("row digit uniqness constraint\n")
-			return false
-		}
-	}
-
-	// column digit uniqueness constraint
-	for r := 0; r < rows; r++ {
-		if g[r][col] == digit {
-			fmt.Printf("column digit uniqueness constraint\n")
-			return false
-		}
-	}
-
-	// subregion digit uniqueness constraint
-	// find upper left corner of subregion: (r0,c0)
-	r0 := (row / 3) * 3
-	c0 := (col / 3) * 3
-	for r := r0; r < r0+3; r++ {
-		for c := c0; c < c0+3; c++ {
-			if g[r][c] == digit {
-				fmt.Printf("subregion digit uniqueness constraint\n")
-				return false
-			}
-		}
-	}
-	return true
+	return rules.Check(g, row, col, digit)
 }
 
 // set sets a digit at a specific location in the grid
@@ -634,6 +801,16 @@ func NewSudoku(r *http.Request, sudoku *SudokuT, s *Grid) {
 // solveSudokuSubmit processes the Sudoku form submission for the solve option
 func solveSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 
+	// A complete board only needs checking
+	if completeBoardSubmit(w, r) {
+		return
+	}
+
+	// Entries that break the rules are only replaced once the player confirms
+	if confirmSolveSubmit(w, r) {
+		return
+	}
+
 	// SudokuT to use in HTML parse and execute
 	// Grid to use in solver functions
 
@@ -644,67 +821,25 @@ func solveSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 	var s Grid
 
 	NewSudoku(r, &sudoku, &s)
+	givens := sudoku.Givens()
 
-	// seed the random number generator
-	rand.Seed(time.Now().Unix())
-
-	// trials or attempts to solve the Sudoku puzzle
-	trial := 0
-	results := make(chan result)
-	begin := time.Now()
-	fmt.Printf("\nStart time: %v\n", begin.Format(time.StampMilli))
-trials:
-	for trial < nTrials {
-		trial++
-		fmt.Printf("Trial %v\n", trial)
-		nsets := 0
-		// loop for nsets
-	sets:
-		for {
-			// launch a goroutine for each 3x3 subregion to find results
-			for r := 0; r < rows; r += rows / 3 {
-				for c := 0; c < cols; c += cols / 3 {
-					go s.getResult(int(r), int(c), results)
-				}
-			}
-
-			nchoices := 10 // how many digits available for this cell in a sub-region
-			var cell result
-			noneAssigned := 0 // number of subregions that are completely assigned values
-			// Collect results and find subregion with smallest number of satisfying digits
-			for i := 0; i < rows; i++ {
-				r := <-results
-				if r.notAssigned == 0 {
-					noneAssigned++
-				} else if r.nchoices < nchoices {
-					nchoices = r.nchoices
-					cell = r
-				}
-			}
-
-			// puzzle solved if all cells filled with valid values
-			if noneAssigned == rows {
-				// Show the Sudoku board that is the solution
-				fmt.Printf("\n                Solved Sudoku                    \n")
-				break trials
-			}
+	// User constraints require the exhaustive solver to prove there is no solution
+	if fv := r.FormValue("constraints"); len(strings.TrimSpace(fv)) > 0 {
+		sudoku.Constraints = fv
+		solveConstrainedSubmit(w, &sudoku)
+		return
+	}
 
-			// no solution if nchoices is zero in any subregion with unassigned cells
-			// start a new trial
-			if nchoices == 0 {
-				NewSudoku(r, &sudoku, &s)
-				fmt.Printf("Number of sets done for trial %v is %v. Start new trial.\n",
-					trial, nsets)
-				break sets
-			}
+	// random number generator for the choices, all randomness comes from it
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-			// Assign a random value for the cell and continue this trial
-			n := rand.Intn(nchoices)
-			s.Set(cell.y, cell.x, cell.choices[n])
-			nsets++
-		}
+	begin := time.Now()
+	trial, propagation, backtracking, ok := s.trialSolve(r.Context(), rng)
+	elapsed := time.Since(begin)
+	if r.Context().Err() != nil {
+		fmt.Printf("\nSolve cancelled after %v trials: %v\n", trial, r.Context().Err())
+		return
 	}
-	fmt.Printf("\nEnd time: %v, run time: %v\n", time.Now().Format(time.StampMilli), time.Since(begin))
 
 	// Copy solution in s into sudoku
 	// Loop over the rows/columns, get the Request form values, insert into sudoku
@@ -716,16 +851,58 @@ trials:
 			val := r.FormValue(name + "_ro")
 			if len(val) > 0 {
 				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
-			} else {
+			} else if s[row][col] > 0 {
 				val := strconv.Itoa(s[row][col])
 				sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
+			} else {
+				sudoku.Grid[name] = Cell{Name: name, Value: "", Invalid: "valid", Readonly: ""}
 			}
 		}
 	}
 
-	// Set puzzle status
-	sudoku.Status.Message = "Status: Valid Puzzle"
-	sudoku.Status.State = "validstatus"
+	// Set puzzle status with the time spent in each phase of the solve;
+	// backtracking covers the random assignments and trial restarts
+	if ok {
+		sudoku.Status.Message = fmt.Sprintf("Status: Solved (%s) in %v (propagation %v, backtracking %v)",
+			givens.Confidence(), elapsed.Round(time.Microsecond), propagation.Round(time.Microsecond),
+			backtracking.Round(time.Microsecond))
+		sudoku.Status.State = "validstatus"
+	} else {
+		sudoku.Status.Message = fmt.Sprintf("Status: No solution found in %d trials", trial)
+		sudoku.Status.State = "invalidstatus"
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// solveConstrainedSubmit solves the givens of sudoku so that every cell respects
+// the user constraints, reporting when the constraints leave no solution
+func solveConstrainedSubmit(w http.ResponseWriter, sudoku *SudokuT) {
+	c, err := ParseConstraints(sudoku.Constraints)
+	if err != nil {
+		sudoku.Status.Message = "Status: " + err.Error()
+		sudoku.Status.State = "invalidstatus"
+	} else if sols := sudoku.Givens().ConstrainedSolutions(&c, 1); len(sols) == 0 {
+		sudoku.Status.Message = "Status: No solution under your constraints"
+		sudoku.Status.State = "invalidstatus"
+	} else {
+		// Copy solution into the non-readonly cells
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				subgrid := (row/3)*3 + col/3
+				name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+				if sudoku.Grid[name].Readonly == "" {
+					val := strconv.Itoa(sols[0][row][col])
+					sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
+				}
+			}
+		}
+		sudoku.Status.Message = "Status: Solved under your constraints"
+		sudoku.Status.State = "solvedstatus"
+	}
 
 	// Write to HTTP output using template and grid
 	if err := t.Execute(w, sudoku); err != nil {
@@ -733,9 +910,118 @@ trials:
 	}
 }
 
+// lockSudokuSubmit processes the Sudoku form submission for the lock option.
+// The user entries become readonly givens of a new puzzle provided the
+// board obeys the rules and still has a solution.
+func lockSudokuSubmit(w http.ResponseWriter, r *http.Request) {
+
+	var (
+		s      Grid // Grid to use in solver functions
+		sudoku SudokuT
+		bad    bool // an entry is not a digit 1-9
+	)
+	sudoku.Grid = make(map[string]Cell)
+
+	// Loop over the rows/columns, get the Request form values, insert into the grid
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+			// Check for readonly cell first by appending "_ro"
+			if val := r.FormValue(name + "_ro"); len(val) > 0 {
+				d, err := strconv.Atoi(val)
+				if err != nil || !validDigit(d) {
+					http.Error(w, fmt.Sprintf("Bad form submission: given %s is %q: %v", cellName(row, col), val, errInvalDig),
+						http.StatusBadRequest)
+					return
+				}
+				sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
+				s[row][col] = d
+				continue
+			}
+			val := r.FormValue(name)
+			sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "valid", Readonly: ""}
+			if len(val) > 0 {
+				if n, err := strconv.Atoi(val); err == nil && validDigit(n) {
+					s[row][col] = n
+				} else {
+					sudoku.Grid[name] = Cell{Name: name, Value: val, Invalid: "invalid", Readonly: ""}
+					bad = true
+				}
+			}
+		}
+	}
+
+	// Set puzzle status, locking the entries only for a consistent board
+	consistent := !bad && s.GivensConsistent()
+	nsol := 0
+	if consistent {
+		nsol = s.CountSolutions(2)
+	}
+	switch {
+	case !consistent:
+		sudoku.Status.Message = "Status: Cannot lock, entries break the rules"
+		sudoku.Status.State = "invalidstatus"
+	case nsol == 0:
+		sudoku.Status.Message = "Status: Cannot lock, puzzle has no solution"
+		sudoku.Status.State = "invalidstatus"
+	default:
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				subgrid := (row/3)*3 + col/3
+				name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+				if s[row][col] > 0 {
+					val := strconv.Itoa(s[row][col])
+					sudoku.Grid[name] = Cell{Name: name + "_ro", Value: val, Invalid: "valid", Readonly: "readonly"}
+				}
+			}
+		}
+		if nsol == 1 {
+			sudoku.Status.Message = "Status: Locked, puzzle has a unique solution"
+		} else {
+			sudoku.Status.Message = "Status: Locked, puzzle has multiple solutions"
+		}
+		sudoku.Status.State = "validstatus"
+	}
+
+	// Write to HTTP output using template and grid
+	if err := t.Execute(w, sudoku); err != nil {
+		log.Fatalf("Write to HTTP output using template with grid error: %v\n", err)
+	}
+}
+
+// duplicateCell returns the first cell the form sends more than one value
+// for, either the same field twice or both the editable and readonly fields.
+// It returns "" for a well formed submission.
+func duplicateCell(r *http.Request) string {
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			subgrid := (row/3)*3 + col/3
+			name := fmt.Sprintf("%d_%d_%d", row, col, subgrid)
+			if len(r.Form[name])+len(r.Form[name+"_ro"]) > 1 {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// formActions lists the actions handleSudokuSubmit accepts
+var formActions = []string{"evaluate", "reset", "new", "solve", "lock", "replay", "heatmap", "importurl", "hint", "countmistakes", "solveanddiff", "forced"}
+
 // handleSudokuSubmit processes the Sudoku form submissions
 func handleSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 
+	// Reject a tampered form holding several values for one cell
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad form submission: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if name := duplicateCell(r); name != "" {
+		http.Error(w, "Bad form submission: more than one value for cell "+name, http.StatusBadRequest)
+		return
+	}
+
 	// Choose an action to take
 	switch r.FormValue("action") {
 	case "evaluate":
@@ -746,14 +1032,67 @@ func handleSudokuSubmit(w http.ResponseWriter, r *http.Request) {
 		newSudokuSubmit(w, r)
 	case "solve":
 		solveSudokuSubmit(w, r)
+	case "lock":
+		lockSudokuSubmit(w, r)
+	case "replay":
+		replaySudokuSubmit(w, r)
+	case "heatmap":
+		heatmapSudokuSubmit(w, r)
+	case "importurl":
+		importURLSubmit(w, r)
+	case "hint":
+		hintSudokuSubmit(w, r)
+	case "countmistakes":
+		countMistakesSubmit(w, r)
+	case "solveanddiff":
+		solveAndDiffSubmit(w, r)
+	case "forced":
+		forcedSudokuSubmit(w, r)
 	default:
-		log.Fatalf("Invalid action for form submission: %v\n", r.FormValue("action"))
+		http.Error(w, fmt.Sprintf("Bad form submission: invalid action %q, want one of %s",
+			r.FormValue("action"), strings.Join(formActions, ", ")), http.StatusBadRequest)
 	}
 }
 
 func main() {
+	flag.Parse()
+	if *badEntries != "keep" && *badEntries != "clear" {
+		log.Fatalf("-badentries must be keep or clear, not %q\n", *badEntries)
+	}
+	if code := selfTestExit(*selfTestRun, *gridFiles); code != 0 {
+		os.Exit(code)
+	}
+	initClaimKey(*claimSecret)
+
 	// Setup http server with handlers for initial connection and form submissions
+	http.HandleFunc("/", handleRoot)
 	http.HandleFunc(pattern, handleSudoku)
 	http.HandleFunc(patternSubmit, handleSudokuSubmit)
-	http.ListenAndServe(addr, nil)
+	http.HandleFunc(pattern12, handleSudoku12)
+	http.HandleFunc(pattern12Submit, handleSudoku12Submit)
+	http.HandleFunc(patternMinimalClues, handleMinimalClues)
+	http.HandleFunc(patternExplain, handleExplain)
+	http.HandleFunc(patternMaxBlanks, handleMaxBlanks)
+	http.HandleFunc(patternGenStats, handleGenStats)
+	http.HandleFunc(patternLadder, handleLadder)
+	http.HandleFunc(patternNew, handleNew)
+	http.HandleFunc(patternSolve, handleSolve)
+	http.HandleFunc(patternRenderText, handleRenderText)
+	http.HandleFunc(patternFingerprint, handleFingerprint)
+	http.HandleFunc(patternScramble, handleScramble)
+	http.HandleFunc(patternImportJSON, handleImportJSON)
+	http.HandleFunc(patternPrint, handlePrint)
+	http.HandleFunc(patternImportOCR, handleImportOCR)
+	http.HandleFunc(patternPreview, handlePreview)
+	http.HandleFunc(patternGrade, handleGrade)
+	http.HandleFunc(patternGradeBatch, handleGradeBatch)
+	http.HandleFunc(patternIsProper, handleIsProper)
+	http.HandleFunc(patternSolveFrames, handleSolveFrames)
+	http.HandleFunc(patternDigitProgress, handleDigitProgress)
+	http.HandleFunc(patternExportSS, handleExportSS)
+	http.HandleFunc(patternImportSS, handleImportSS)
+	http.HandleFunc(patternCheckNotes, handleCheckNotes)
+	http.HandleFunc(patternClaim, handleClaim)
+	http.Handle(patternStatic, http.StripPrefix(patternStatic, http.FileServer(http.FS(static))))
+	http.ListenAndServe(addr, withCORS(http.DefaultServeMux))
 }
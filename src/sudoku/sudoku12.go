@@ -0,0 +1,357 @@
+/*
+ 12x12 Sudoku variant with 3x4 boxes.
+ Every row, column, and box of three rows by four columns holds the
+ digits 1-9 and A, B, C once each.  The units come from gridUnits like
+ those of the classic grid, and the variant has its own page, solver,
+ and validator since Grid is fixed at 9x9.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"math/bits"
+	"net/http"
+	"strings"
+)
+
+const (
+	size12          = 12                        // rows, columns, and digits of the variant
+	boxRows12       = 3                         // rows of a box
+	boxCols12       = 4                         // columns of a box
+	allDigits12     = uint16(0x1ffe)            // bits 1-12 set, one per digit
+	digits12        = "123456789ABC"            // digit symbols, 10-12 are A-C
+	pattern12       = "/sudoku12"               // 12x12 page
+	pattern12Submit = "/sudoku12-submit"        // 12x12 form submissions
+	tmpl12          = "templates/sudoku12.html" // 12x12 template in assets
+	grid12File      = "grids12/sudoku12.txt"    // initial 12x12 puzzle in assets
+	maxDepth12      = size12 * size12           // recursion limit of the solver, one level per cell
+)
+
+var errGrid12Format = errors.New("grid must be 144 characters of 1-9 and A-C with 0 or . for empty cells")
+
+// Grid12 holds the digits 1-12 of a 12x12 grid, 0 for empty cells
+type Grid12 [size12][size12]int
+
+// SudokuT12 is the template data of the 12x12 page, cells in reading order
+type SudokuT12 struct {
+	Cells  []Cell
+	Status Status
+}
+
+var (
+	units12     = gridUnits(size12, boxRows12, boxCols12)
+	cellUnits12 = func() (cu [size12][size12][]int) {
+		for i, u := range units12 {
+			for _, rc := range u.Cells {
+				cu[rc[0]][rc[1]] = append(cu[rc[0]][rc[1]], i)
+			}
+		}
+		return cu
+	}()
+	t12 *template.Template // 12x12 page, parsed in init
+)
+
+// parseDigit12 returns the digit 1-12 of the symbol, or 0 when it is none
+func parseDigit12(s string) int {
+	if len(s) != 1 {
+		return 0
+	}
+	return strings.IndexByte(digits12, strings.ToUpper(s)[0]) + 1
+}
+
+// formatDigit12 returns the symbol of a digit 1-12, or "" for an empty cell
+func formatDigit12(d int) string {
+	if d < 1 || d > size12 {
+		return ""
+	}
+	return digits12[d-1 : d]
+}
+
+// ParseGrid12 converts 144 characters in row order into a Grid12.
+// 1-9 and A-C are givens, '0' and '.' are empty cells.
+func ParseGrid12(s string) (Grid12, error) {
+	var g Grid12
+	s = strings.Join(strings.Fields(s), "")
+	if len(s) != size12*size12 {
+		return g, errGrid12Format
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '0' || s[i] == '.' {
+			continue
+		}
+		d := parseDigit12(s[i : i+1])
+		if d == 0 {
+			return g, errGrid12Format
+		}
+		g[i/size12][i%size12] = d
+	}
+	return g, nil
+}
+
+// String returns the grid as 144 characters with 0 for empty cells
+func (g Grid12) String() string {
+	var b strings.Builder
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			if g[row][col] == 0 {
+				b.WriteByte('0')
+			} else {
+				b.WriteString(formatDigit12(g[row][col]))
+			}
+		}
+	}
+	return b.String()
+}
+
+// Conflicts returns the filled cells that share their digit with another
+// cell of a row, column, or box, in reading order
+func (g Grid12) Conflicts() [][2]int {
+	var bad [size12][size12]bool
+	for _, u := range units12 {
+		var seen [size12 + 1]int
+		for _, rc := range u.Cells {
+			seen[g[rc[0]][rc[1]]]++
+		}
+		for _, rc := range u.Cells {
+			if d := g[rc[0]][rc[1]]; d != 0 && seen[d] > 1 {
+				bad[rc[0]][rc[1]] = true
+			}
+		}
+	}
+	var cells [][2]int
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			if bad[row][col] {
+				cells = append(cells, [2]int{row, col})
+			}
+		}
+	}
+	return cells
+}
+
+// solver12 holds the search state for a backtracking solve of a Grid12
+type solver12 struct {
+	g     Grid12
+	used  [3 * size12]uint16 // digits used in each row, column, and box
+	limit int                // stop after this many solutions
+	sols  []Grid12           // solutions found
+	depth int                // current recursion depth
+	max   int                // recursion depth limit
+	err   error              // errDepth when the search stopped at the limit
+}
+
+// usedAt returns the digits used in the units holding the cell
+func (sv *solver12) usedAt(row, col int) uint16 {
+	var used uint16
+	for _, u := range cellUnits12[row][col] {
+		used |= sv.used[u]
+	}
+	return used
+}
+
+// search fills empty cells depth first, choosing the cell with the fewest
+// candidates, and returns true when the solution limit is reached or the
+// search stops at the depth limit
+func (sv *solver12) search() bool {
+	if sv.depth > sv.max {
+		sv.err = errDepth
+		return true
+	}
+	bestRow, bestCol := -1, -1
+	var bestMask uint16
+	min := size12 + 1
+	for row := 0; row < size12 && min > 1; row++ {
+		for col := 0; col < size12; col++ {
+			if sv.g[row][col] != 0 {
+				continue
+			}
+			mask := allDigits12 &^ sv.usedAt(row, col)
+			if n := bits.OnesCount16(mask); n < min {
+				min, bestRow, bestCol, bestMask = n, row, col, mask
+				if n <= 1 {
+					break
+				}
+			}
+		}
+	}
+	if bestRow < 0 {
+		sv.sols = append(sv.sols, sv.g)
+		return len(sv.sols) >= sv.limit
+	}
+	for d := 1; d <= size12; d++ {
+		bit := uint16(1) << d
+		if bestMask&bit == 0 {
+			continue
+		}
+		sv.g[bestRow][bestCol] = d
+		for _, u := range cellUnits12[bestRow][bestCol] {
+			sv.used[u] |= bit
+		}
+		sv.depth++
+		done := sv.search()
+		sv.depth--
+		for _, u := range cellUnits12[bestRow][bestCol] {
+			sv.used[u] &^= bit
+		}
+		sv.g[bestRow][bestCol] = 0
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+// Solutions returns up to limit solutions of the grid, none when the
+// givens break the rules
+func (g Grid12) Solutions(limit int) []Grid12 {
+	sols, _ := g.SolutionsWithin(limit, maxDepth12)
+	return sols
+}
+
+// SolutionsWithin returns up to limit solutions of the grid, or errDepth
+// with the solutions found so far when the search needs to recurse deeper
+// than depth
+func (g Grid12) SolutionsWithin(limit, depth int) ([]Grid12, error) {
+	if limit < 1 || len(g.Conflicts()) > 0 {
+		return nil, nil
+	}
+	sv := &solver12{g: g, limit: limit, max: depth}
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			if d := g[row][col]; d != 0 {
+				for _, u := range cellUnits12[row][col] {
+					sv.used[u] |= 1 << d
+				}
+			}
+		}
+	}
+	sv.search()
+	return sv.sols, sv.err
+}
+
+// cellName12 returns the form field name of a cell, row_col_box as on the 9x9 page
+func cellName12(row, col int) string {
+	return fmt.Sprintf("%d_%d_%d", row, col, (row/boxRows12)*(size12/boxCols12)+col/boxCols12)
+}
+
+// page12 fills the template data with the givens as readonly cells and
+// the other digits of board as player entries
+func page12(givens, board Grid12) SudokuT12 {
+	var sudoku SudokuT12
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			name := cellName12(row, col)
+			if givens[row][col] != 0 {
+				sudoku.Cells = append(sudoku.Cells, Cell{Name: name + "_ro", Value: formatDigit12(givens[row][col]), Invalid: "valid", Readonly: "readonly"})
+			} else {
+				sudoku.Cells = append(sudoku.Cells, Cell{Name: name, Value: formatDigit12(board[row][col]), Invalid: "valid"})
+			}
+		}
+	}
+	sudoku.Status.Message = "Status: Valid Puzzle"
+	sudoku.Status.State = "validstatus"
+	return sudoku
+}
+
+// readBoard12 reads the givens and the digits the player entered from the
+// 12x12 form, and the names of the cells whose entry or given is not a
+// digit 1-9 or A-C.  A bad given, which only a tampered form can hold, is
+// left empty in givens.
+func readBoard12(r *http.Request) (givens, board Grid12, bad []string) {
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			name := cellName12(row, col)
+			if val := r.FormValue(name + "_ro"); len(val) > 0 {
+				if givens[row][col] = parseDigit12(val); givens[row][col] == 0 {
+					bad = append(bad, name)
+				}
+				board[row][col] = givens[row][col]
+			} else if val := r.FormValue(name); len(val) > 0 {
+				if board[row][col] = parseDigit12(val); board[row][col] == 0 {
+					bad = append(bad, name)
+				}
+			}
+		}
+	}
+	return givens, board, bad
+}
+
+// handleSudoku12 processes the initial 12x12 connection
+func handleSudoku12(w http.ResponseWriter, r *http.Request) {
+	data, err := assets.ReadFile(grid12File)
+	if err != nil {
+		log.Printf("Error reading %s: %v\n", grid12File, err)
+		http.Error(w, "Bad puzzle file "+grid12File, http.StatusInternalServerError)
+		return
+	}
+	g, err := ParseGrid12(string(data))
+	if err != nil {
+		log.Printf("Error reading %s: %v\n", grid12File, err)
+		http.Error(w, "Bad puzzle file "+grid12File, http.StatusInternalServerError)
+		return
+	}
+	if err := t12.Execute(w, page12(g, g)); err != nil {
+		log.Printf("Write 12x12 page error: %v\n", err)
+	}
+}
+
+// handleSudoku12Submit processes the evaluate, reset, and solve options of the 12x12 form
+func handleSudoku12Submit(w http.ResponseWriter, r *http.Request) {
+	givens, board, bad := readBoard12(r)
+	var sudoku SudokuT12
+	switch r.FormValue("action") {
+	case "evaluate":
+		sudoku = page12(givens, board)
+		marked := make(map[string]bool)
+		for _, name := range bad {
+			marked[name] = true
+		}
+		conflicts := board.Conflicts()
+		for _, rc := range conflicts {
+			marked[cellName12(rc[0], rc[1])] = true
+		}
+		for i, cell := range sudoku.Cells {
+			if marked[cell.Name] {
+				// keep the text of entries and givens that are not digits
+				if cell.Value == "" {
+					sudoku.Cells[i].Value = r.FormValue(cell.Name)
+				}
+				if cell.Value == "" && sudoku.Cells[i].Value == "" {
+					sudoku.Cells[i].Value = r.FormValue(cell.Name + "_ro")
+				}
+				sudoku.Cells[i].Invalid = "invalid"
+			}
+		}
+		switch {
+		case len(bad) > 0 || len(conflicts) > 0:
+			sudoku.Status.Message = fmt.Sprintf("Status: Invalid, %d conflicting cells, %d entries not 1-9 or A-C", len(conflicts), len(bad))
+			sudoku.Status.State = "invalidstatus"
+		case !strings.Contains(board.String(), "0"):
+			sudoku.Status.Message = "Status: Solved Puzzle"
+			sudoku.Status.State = "solvedstatus"
+		}
+	case "reset":
+		sudoku = page12(givens, givens)
+	case "solve":
+		sols := givens.Solutions(1)
+		if len(sols) == 0 {
+			sudoku = page12(givens, board)
+			sudoku.Status.Message = "Status: No solution"
+			sudoku.Status.State = "invalidstatus"
+		} else {
+			sudoku = page12(givens, sols[0])
+			sudoku.Status.Message = "Status: Solved"
+		}
+	default:
+		http.Error(w, fmt.Sprintf("Bad form submission: invalid action %q, want one of evaluate, reset, solve",
+			r.FormValue("action")), http.StatusBadRequest)
+		return
+	}
+	if err := t12.Execute(w, sudoku); err != nil {
+		log.Printf("Write 12x12 page error: %v\n", err)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// puzzle12 is the bundled 12x12 puzzle, which has a unique solution
+const puzzle12 = "8000C0000007" + "09000B700000" + "00B30500C060" + "0200000A0006" +
+	"B00070004100" + "A01000007302" + "000069000005" + "300000010400" +
+	"050080A06030" + "000000300240" + "00250009B000" + "700B0014080C"
+
+func TestGridUnits12(t *testing.T) {
+	units := gridUnits(size12, boxRows12, boxCols12)
+	if len(units) != 3*size12 {
+		t.Fatalf("got %d units, want %d", len(units), 3*size12)
+	}
+	tests := []struct {
+		unit  int
+		first [2]int
+		last  [2]int
+	}{
+		{0, [2]int{0, 0}, [2]int{0, 11}},             // row 0
+		{size12 + 5, [2]int{0, 5}, [2]int{11, 5}},    // column 5
+		{2 * size12, [2]int{0, 0}, [2]int{2, 3}},     // first box
+		{2*size12 + 2, [2]int{0, 8}, [2]int{2, 11}},  // last box of the first band
+		{2*size12 + 3, [2]int{3, 0}, [2]int{5, 3}},   // first box of the second band
+		{3*size12 - 1, [2]int{9, 8}, [2]int{11, 11}}, // last box
+	}
+	for _, tt := range tests {
+		cells := units[tt.unit].Cells
+		if len(cells) != size12 || cells[0] != tt.first || cells[len(cells)-1] != tt.last {
+			t.Errorf("unit %d: %d cells from %v to %v, want 12 from %v to %v",
+				tt.unit, len(cells), cells[0], cells[len(cells)-1], tt.first, tt.last)
+		}
+	}
+}
+
+func TestParseGrid12(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{puzzle12, false},
+		{strings.ToLower(puzzle12), false},
+		{strings.Replace(puzzle12, "0", ".", -1), false},
+		{puzzle12[:143], true},
+		{"D" + puzzle12[1:], true},
+	}
+	for _, tt := range tests {
+		g, err := ParseGrid12(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGrid12(%.20q...) error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && g.String() != puzzle12 {
+			t.Errorf("ParseGrid12(%.20q...) = %s, want %s", tt.in, g, puzzle12)
+		}
+	}
+}
+
+func TestSolutions12(t *testing.T) {
+	g, err := ParseGrid12(puzzle12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sols := g.Solutions(2)
+	if len(sols) != 1 {
+		t.Fatalf("got %d solutions, want 1", len(sols))
+	}
+	sol := sols[0]
+	if len(sol.Conflicts()) != 0 || strings.Contains(sol.String(), "0") {
+		t.Fatalf("solution %s is not a complete valid grid", sol)
+	}
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			if g[row][col] != 0 && sol[row][col] != g[row][col] {
+				t.Fatalf("solution changes the given at %d,%d", row, col)
+			}
+		}
+	}
+}
+
+func TestSolutionsWithin12(t *testing.T) {
+	g, err := ParseGrid12(puzzle12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empties := strings.Count(puzzle12, "0")
+	tests := []struct {
+		name  string
+		g     Grid12
+		limit int
+		depth int
+		sols  int
+		err   error
+	}{
+		{"puzzle", g, 2, maxDepth12, 1, nil},
+		{"puzzle at its empty cells", g, 2, empties, 1, nil},
+		{"puzzle one short", g, 2, empties - 1, 0, errDepth},
+		{"empty grid", Grid12{}, 1, maxDepth12, 1, nil},
+		{"empty grid shallow", Grid12{}, 1, 10, 0, errDepth},
+	}
+	for _, tt := range tests {
+		sols, err := tt.g.SolutionsWithin(tt.limit, tt.depth)
+		if len(sols) != tt.sols || err != tt.err {
+			t.Errorf("%s: %d solutions, error %v, want %d, %v", tt.name, len(sols), err, tt.sols, tt.err)
+		}
+	}
+}
+
+func TestReadBoard12(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		val   string
+		given int // of the first cell
+		entry int // of the second cell
+		bad   []string
+	}{
+		{"given", cellName12(0, 0) + "_ro", "8", 8, 0, nil},
+		{"given C", cellName12(0, 0) + "_ro", "c", 12, 0, nil},
+		{"bad given", cellName12(0, 0) + "_ro", "D", 0, 0, []string{cellName12(0, 0)}},
+		{"given 10", cellName12(0, 0) + "_ro", "10", 0, 0, []string{cellName12(0, 0)}},
+		{"entry", cellName12(0, 1), "B", 0, 11, nil},
+		{"bad entry", cellName12(0, 1), "0", 0, 0, []string{cellName12(0, 1)}},
+	}
+	for _, tt := range tests {
+		form := url.Values{tt.field: {tt.val}}
+		req := httptest.NewRequest(http.MethodPost, pattern12Submit, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		givens, board, bad := readBoard12(req)
+		if givens[0][0] != tt.given || board[0][0] != tt.given || board[0][1] != tt.entry || !reflect.DeepEqual(bad, tt.bad) {
+			t.Errorf("%s: given %d, entry %d, bad %v, want %d, %d, %v", tt.name, givens[0][0], board[0][1], bad, tt.given, tt.entry, tt.bad)
+		}
+	}
+}
+
+func TestConflicts12(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells map[[2]int]int
+		want  [][2]int
+	}{
+		{"empty", nil, nil},
+		{"same box only", map[[2]int]int{{0, 0}: 12, {2, 3}: 12}, [][2]int{{0, 0}, {2, 3}}},
+		{"next box across", map[[2]int]int{{0, 3}: 5, {1, 4}: 5}, nil},
+		{"next box down", map[[2]int]int{{2, 0}: 5, {3, 1}: 5}, nil},
+		{"row", map[[2]int]int{{4, 0}: 10, {4, 11}: 10}, [][2]int{{4, 0}, {4, 11}}},
+		{"column", map[[2]int]int{{0, 7}: 1, {11, 7}: 1}, [][2]int{{0, 7}, {11, 7}}},
+	}
+	for _, tt := range tests {
+		var g Grid12
+		for rc, d := range tt.cells {
+			g[rc[0]][rc[1]] = d
+		}
+		got := g.Conflicts()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: Conflicts() = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: Conflicts() = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSudoku12Submit(t *testing.T) {
+	g, _ := ParseGrid12(puzzle12)
+	form := url.Values{}
+	for row := 0; row < size12; row++ {
+		for col := 0; col < size12; col++ {
+			if g[row][col] != 0 {
+				form.Set(cellName12(row, col)+"_ro", formatDigit12(g[row][col]))
+			}
+		}
+	}
+	tests := []struct {
+		action  string
+		entries map[string]string
+		code    int
+		status  string
+	}{
+		{"evaluate", nil, http.StatusOK, "Status: Valid Puzzle"},
+		{"evaluate", map[string]string{cellName12(0, 1): "8"}, http.StatusOK, "Status: Invalid, 2 conflicting cells, 0 entries not 1-9 or A-C"},
+		{"evaluate", map[string]string{cellName12(0, 1): "x"}, http.StatusOK, "Status: Invalid, 0 conflicting cells, 1 entries not 1-9 or A-C"},
+		{"evaluate", map[string]string{cellName12(0, 0) + "_ro": "D"}, http.StatusOK, "Status: Invalid, 0 conflicting cells, 1 entries not 1-9 or A-C"},
+		{"solve", nil, http.StatusOK, "Status: Solved"},
+		{"reset", map[string]string{cellName12(0, 1): "5"}, http.StatusOK, "Status: Valid Puzzle"},
+		{"new", nil, http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		f := url.Values{}
+		for k, v := range form {
+			f[k] = v
+		}
+		f.Set("action", tt.action)
+		for k, v := range tt.entries {
+			f.Set(k, v)
+		}
+		req := httptest.NewRequest(http.MethodPost, pattern12Submit, strings.NewReader(f.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handleSudoku12Submit(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s %v: status code %d, want %d", tt.action, tt.entries, rec.Code, tt.code)
+			continue
+		}
+		if tt.status != "" && !strings.Contains(rec.Body.String(), `value="`+tt.status+`"`) {
+			t.Errorf("%s %v: page does not show %q", tt.action, tt.entries, tt.status)
+		}
+	}
+}
+
+func TestHandleSudoku12(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleSudoku12(rec, httptest.NewRequest(http.MethodGet, pattern12, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status code %d, want 200", rec.Code)
+	}
+	if n := strings.Count(rec.Body.String(), `<input type="text" size="1"`); n != size12*size12 {
+		t.Errorf("page has %d cells, want %d", n, size12*size12)
+	}
+}
@@ -0,0 +1,777 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPuzzle has a unique solution, testSolution
+const (
+	testPuzzle   = "530070000600195000098000060800060003400803001700020006060000280000419005000080079"
+	testSolution = "534678912672195348198342567859761423426853791713924856961537284287419635345286179"
+)
+
+// testStalls is a harder puzzle, one that defeats the techniques of a
+// logical solver
+const testStalls = "100007090030020008009600500005300900010080002600004000300000010040000007007000300"
+
+// mustGrid parses an 81 character grid or fails the test
+func mustGrid(t *testing.T, s string) Grid {
+	t.Helper()
+	g, err := ParseGrid(s)
+	if err != nil {
+		t.Fatalf("ParseGrid(%q): %v", s, err)
+	}
+	return g
+}
+
+// boardForm returns the form the page posts for the givens and the
+// digits of board in the other cells
+func boardForm(givens, board Grid) url.Values {
+	form := url.Values{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			name := fmt.Sprintf("%d_%d_%d", row, col, (row/3)*3+col/3)
+			if givens[row][col] != 0 {
+				form.Set(name+"_ro", strconv.Itoa(givens[row][col]))
+			} else if board[row][col] != 0 {
+				form.Set(name, strconv.Itoa(board[row][col]))
+			}
+		}
+	}
+	return form
+}
+
+// postForm submits the form with the action to handleSudokuSubmit
+func postForm(action string, form url.Values) *httptest.ResponseRecorder {
+	f := url.Values{}
+	for k, v := range form {
+		f[k] = v
+	}
+	f.Set("action", action)
+	req := httptest.NewRequest(http.MethodPost, patternSubmit, strings.NewReader(f.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handleSudokuSubmit(rec, req)
+	return rec
+}
+
+func TestEvaluateKeypad(t *testing.T) {
+	puzzle, solution := mustGrid(t, testPuzzle), mustGrid(t, testSolution)
+	// ones has all nine 1s placed, moved has one of them moved along its
+	// row, where it clashes with the 1 of another column
+	ones := puzzle
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if solution[row][col] == 1 {
+				ones[row][col] = 1
+			}
+		}
+	}
+	moved := ones
+	moved[0][7], moved[0][8] = 0, 1
+	tests := []struct {
+		name  string
+		given string // value of the first given, testPuzzle's when empty
+		board Grid
+		code  int
+		done  int // keys grayed out
+	}{
+		{"puzzle", "", puzzle, http.StatusOK, 0},
+		{"ones placed", "", ones, http.StatusOK, 1},
+		{"ones in conflict", "", moved, http.StatusOK, 0},
+		{"solved", "", solution, http.StatusOK, 9},
+		{"given 10", "10", puzzle, http.StatusBadRequest, 0},
+		{"given -1", "-1", puzzle, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, tt.board)
+		if tt.given != "" {
+			form.Set("0_0_0_ro", tt.given)
+		}
+		rec := postForm("evaluate", form)
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.name, rec.Code, tt.code)
+			continue
+		}
+		if n := strings.Count(rec.Body.String(), `class="key done"`); tt.code == http.StatusOK && n != tt.done {
+			t.Errorf("%s: %d keys done, want %d", tt.name, n, tt.done)
+		}
+	}
+}
+
+func TestEvaluateNextEmpty(t *testing.T) {
+	puzzle, solution := mustGrid(t, testPuzzle), mustGrid(t, testSolution)
+	first := puzzle
+	first[0][2] = 4
+	gap := solution
+	gap[4][4] = 0
+	tests := []struct {
+		name    string
+		board   Grid
+		entries map[string]string
+		focus   string // cell with autofocus, none when empty
+	}{
+		{"puzzle", puzzle, nil, "0_2_0"},
+		{"first filled", first, nil, "0_3_1"},
+		{"gap in the middle", gap, nil, "4_4_4"},
+		{"bad entry is not empty", gap, map[string]string{"0_2_0": "x"}, "4_4_4"},
+		{"solved", solution, nil, ""},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, tt.board)
+		for k, v := range tt.entries {
+			form.Set(k, v)
+		}
+		body := postForm("evaluate", form).Body.String()
+		want := 0
+		if tt.focus != "" {
+			want = 1
+			if !regexp.MustCompile(`name="` + tt.focus + `" value="" class="[^"]*"[^>]* autofocus />`).MatchString(body) {
+				t.Errorf("%s: %s does not have the focus", tt.name, tt.focus)
+			}
+		}
+		if n := strings.Count(body, "autofocus"); n != want {
+			t.Errorf("%s: %d cells have the focus, want %d", tt.name, n, want)
+		}
+	}
+}
+
+// givensRe matches the readonly cells of a page
+var givensRe = regexp.MustCompile(`name="(\d_\d_\d)_ro" value="(\d)"`)
+
+func TestNewSeed(t *testing.T) {
+	tests := []struct {
+		seed   string
+		code   int
+		status string
+	}{
+		{"1", http.StatusOK, "Status: Valid Puzzle, seed 1"},
+		{" 2 ", http.StatusOK, "Status: Valid Puzzle, seed 2"},
+		{"-3", http.StatusOK, "Status: Valid Puzzle, seed -3"},
+		{"", http.StatusOK, "Status: Valid Puzzle, seed "},
+		{"x", http.StatusBadRequest, ""},
+	}
+	pages := make(map[string]string) // givens of each seed
+	for _, tt := range tests {
+		for i := 0; i < 2; i++ {
+			form := url.Values{"blankvalues": {"40"}, "seed": {tt.seed}}
+			rec := postForm("new", form)
+			if rec.Code != tt.code {
+				t.Errorf("seed %q: status code %d, want %d", tt.seed, rec.Code, tt.code)
+				break
+			}
+			if tt.code != http.StatusOK {
+				break
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.status) {
+				t.Errorf("seed %q: page does not show %q", tt.seed, tt.status)
+			}
+			givens := fmt.Sprint(givensRe.FindAllStringSubmatch(body, -1))
+			if prev, ok := pages[tt.seed]; ok && tt.seed != "" && givens != prev {
+				t.Errorf("seed %q: a second puzzle differs from the first", tt.seed)
+			}
+			pages[tt.seed] = givens
+		}
+	}
+	if pages["1"] == pages[" 2 "] {
+		t.Errorf("seeds 1 and 2 generated the same puzzle")
+	}
+}
+
+func TestTrialSolve(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	puzzle := mustGrid(t, testPuzzle)
+	// 0,8 can only hold 9, which column 8 already has
+	noSolution := mustGrid(t, "123456780000000009"+strings.Repeat("0", 63))
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		g      Grid
+		solved bool
+		trials int // 0 for any number
+	}{
+		{"puzzle", context.Background(), puzzle, true, 0},
+		{"solution", context.Background(), mustGrid(t, testSolution), true, 1},
+		{"cancelled", cancelled, puzzle, false, 1},
+		{"no solution", context.Background(), noSolution, false, nTrials},
+	}
+	for _, tt := range tests {
+		g := tt.g
+		trial, _, _, solved := g.trialSolve(tt.ctx, rand.New(rand.NewSource(1)))
+		if solved != tt.solved || tt.trials != 0 && trial != tt.trials {
+			t.Errorf("%s: trialSolve = %d trials, solved %v, want %d, %v", tt.name, trial, solved, tt.trials, tt.solved)
+			continue
+		}
+		if !solved && g != tt.g {
+			t.Errorf("%s: unsolved grid changed to %s", tt.name, g)
+		}
+		if solved && (g.Clues() != rows*cols || !g.GivensConsistent() || !g.RespectsGivens(tt.g)) {
+			t.Errorf("%s: solved grid %s", tt.name, g)
+		}
+	}
+}
+
+// TestTrialSolveTiming checks that the phases trialSolve times add up to
+// no more than the whole solve and that both are measured
+func TestTrialSolveTiming(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+	}{
+		{"puzzle", mustGrid(t, testPuzzle)},
+		{"empty", Grid{}},
+	}
+	for _, tt := range tests {
+		g := tt.g
+		begin := time.Now()
+		_, propagation, backtracking, ok := g.trialSolve(context.Background(), rand.New(rand.NewSource(1)))
+		elapsed := time.Since(begin)
+		if !ok || g.Clues() != rows*cols || !g.GivensConsistent() {
+			t.Errorf("%s: not solved: %s", tt.name, g)
+			continue
+		}
+		if propagation <= 0 || backtracking <= 0 || propagation+backtracking > elapsed {
+			t.Errorf("%s: propagation %v and backtracking %v in a solve of %v", tt.name, propagation, backtracking, elapsed)
+		}
+	}
+}
+
+func TestResultBefore(t *testing.T) {
+	tests := []struct {
+		a, b result
+		want bool
+	}{
+		{result{y: 0, x: 4}, result{y: 1, x: 0}, true},
+		{result{y: 1, x: 0}, result{y: 0, x: 4}, false},
+		{result{y: 3, x: 2}, result{y: 3, x: 5}, true},
+		{result{y: 3, x: 5}, result{y: 3, x: 2}, false},
+		{result{y: 3, x: 5}, result{y: 3, x: 5}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.before(tt.b); got != tt.want {
+			t.Errorf("%d,%d before %d,%d = %v, want %v", tt.a.y, tt.a.x, tt.b.y, tt.b.x, got, tt.want)
+		}
+	}
+}
+
+// TestTrialSolveSeed fills an empty grid twice with each seed and number
+// of workers, which must give the same grid for the same seed
+func TestTrialSolveSeed(t *testing.T) {
+	defer func(n int) { *workers = n }(*workers)
+	tests := []struct {
+		seed    int64
+		workers int
+	}{
+		{1, 1},
+		{1, subgrids},
+		{2, 3},
+	}
+	grids := make(map[int64]Grid)
+	for _, tt := range tests {
+		*workers = tt.workers
+		for i := 0; i < 2; i++ {
+			var g Grid
+			if _, _, _, ok := g.trialSolve(context.Background(), rand.New(rand.NewSource(tt.seed))); !ok {
+				t.Fatalf("seed %d: not solved", tt.seed)
+			}
+			if prev, seen := grids[tt.seed]; !seen {
+				grids[tt.seed] = g
+			} else if g != prev {
+				t.Errorf("seed %d, %d workers: grid %s, want %s", tt.seed, tt.workers, g, prev)
+			}
+		}
+	}
+	if grids[1] == grids[2] {
+		t.Errorf("seeds 1 and 2 both gave %s", grids[1])
+	}
+}
+
+func TestConflictSummary(t *testing.T) {
+	tests := []struct {
+		name      string
+		invalids  []Bad
+		badValues int
+		want      string
+	}{
+		{"none", nil, 0, "0 row, 0 column, 0 box conflicts"},
+		{"one of each", []Bad{{"row", 0, "5"}, {"col", 2, "5"}, {"subgrid", 0, "5"}}, 0, "1 row, 1 column, 1 box conflicts"},
+		{"duplicates count once", []Bad{{"row", 0, "5"}, {"row", 0, "5"}, {"row", 1, "5"}}, 0, "2 row, 0 column, 0 box conflicts"},
+		{"bad values", nil, 2, "0 row, 0 column, 0 box conflicts, 2 bad values"},
+	}
+	for _, tt := range tests {
+		if got := conflictSummary(tt.invalids, tt.badValues); got != tt.want {
+			t.Errorf("%s: conflictSummary = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateConflicts(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name    string
+		entries map[string]string
+		status  string
+	}{
+		{"valid", nil, "Status: Valid Puzzle"},
+		{"row and box", map[string]string{"0_2_0": "5"}, "Status: Invalid, 1 row, 0 column, 1 box conflicts"},
+		{"column", map[string]string{"2_0_0": "7"}, "Status: Invalid, 0 row, 1 column, 0 box conflicts"},
+		{"bad value", map[string]string{"0_2_0": "x"}, "Status: Invalid, 0 row, 0 column, 0 box conflicts, 1 bad values"},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		for k, v := range tt.entries {
+			form.Set(k, v)
+		}
+		rec := postForm("evaluate", form)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status code %d, want 200", tt.name, rec.Code)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), `value="`+tt.status+`"`) {
+			t.Errorf("%s: page does not show %q", tt.name, tt.status)
+		}
+	}
+}
+
+// crowdedRules adds a unit listing R1C3 many times, which must not
+// overflow the evaluator's histogram counts
+type crowdedRules struct {
+	StandardRules
+	repeats int
+}
+
+func (cr crowdedRules) Units() []Unit {
+	u := Unit{Kind: "row", Num: rows}
+	for i := 0; i < cr.repeats; i++ {
+		u.Cells = append(u.Cells, [2]int{0, 2})
+	}
+	return append(append([]Unit{}, standardUnits...), u)
+}
+
+func TestEvaluateCrowdedUnit(t *testing.T) {
+	defer func(rs RuleSet) { rules = rs }(rules)
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		repeats int
+		status  string
+	}{
+		{1, "Status: Valid Puzzle"},
+		{2, "Status: Invalid, 1 row, 0 column, 0 box conflicts"},
+		{128, "Status: Invalid, 1 row, 0 column, 0 box conflicts"},
+		{300, "Status: Invalid, 1 row, 0 column, 0 box conflicts"},
+	}
+	for _, tt := range tests {
+		rules = crowdedRules{repeats: tt.repeats}
+		form := boardForm(puzzle, puzzle)
+		form.Set("0_2_0", "4")
+		rec := postForm("evaluate", form)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `value="`+tt.status+`"`) {
+			t.Errorf("%d repeats: status code %d, page does not show %q", tt.repeats, rec.Code, tt.status)
+			continue
+		}
+		class := "valid"
+		if tt.repeats > 1 {
+			class = "invalid"
+		}
+		if cell := `name="0_2_0" value="4" class="` + class + `"`; !strings.Contains(body, cell) {
+			t.Errorf("%d repeats: page does not show %s", tt.repeats, cell)
+		}
+	}
+}
+
+func TestStaticAssets(t *testing.T) {
+	h := http.StripPrefix(patternStatic, http.FileServer(http.FS(static)))
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+	}{
+		{"/static/css/style.css", http.StatusOK, "text/css; charset=utf-8"},
+		{"/static/css/missing.css", http.StatusNotFound, ""},
+		{"/static/sudoku.go", http.StatusNotFound, ""},
+		{"/static/templates/sudoku.html", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+	}
+}
+
+func TestHandleSudokuWorkingDirectory(t *testing.T) {
+	// the template and the initial grid are compiled in, so the page does
+	// not depend on the directory the server starts in
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	rec := httptest.NewRecorder()
+	handleSudoku(rec, httptest.NewRequest(http.MethodGet, pattern, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `value="Status: Valid Puzzle"`) {
+		t.Errorf("status code %d, page does not show the puzzle", rec.Code)
+	}
+}
+
+func TestSolveConstrained(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		constraints string
+		status      string
+	}{
+		{"r1c3=4", "Status: Solved under your constraints"},
+		{"r1c3=12", "Status: No solution under your constraints"},
+		{"r0c3=1", `Status: constraint &#34;r0c3=1&#34;`},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		form.Set("constraints", tt.constraints)
+		rec := postForm("solve", form)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: status code %d, want 200", tt.constraints, rec.Code)
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), `value="`+tt.status) {
+			t.Errorf("%q: page does not show %q", tt.constraints, tt.status)
+		}
+	}
+}
+
+func TestLock(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	var few Grid
+	few[0][0], few[4][4] = 5, 7
+	tests := []struct {
+		name    string
+		board   Grid
+		entries map[string]string
+		status  string
+		givens  int // readonly cells on the page
+	}{
+		{"unique", puzzle, nil, "Status: Locked, puzzle has a unique solution", puzzle.Clues()},
+		{"multiple", few, nil, "Status: Locked, puzzle has multiple solutions", 2},
+		{"no solution", puzzle, map[string]string{"0_2_0": "1"}, "Status: Cannot lock, puzzle has no solution", 0},
+		{"conflict", puzzle, map[string]string{"0_2_0": "5"}, "Status: Cannot lock, entries break the rules", 0},
+		{"bad entry", puzzle, map[string]string{"0_2_0": "x"}, "Status: Cannot lock, entries break the rules", 0},
+	}
+	for _, tt := range tests {
+		form := boardForm(Grid{}, tt.board)
+		for k, v := range tt.entries {
+			form.Set(k, v)
+		}
+		rec := postForm("lock", form)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `value="`+tt.status+`"`) {
+			t.Errorf("%s: status code %d, page does not show %q", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if n := strings.Count(body, `_ro"`); n != tt.givens {
+			t.Errorf("%s: %d givens after lock, want %d", tt.name, n, tt.givens)
+		}
+	}
+}
+
+func TestFormActions(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		action string
+		valid  bool
+	}{
+		{"", false},
+		{"delete", false},
+		{"Evaluate", false},
+		{"evaluate ", false},
+	}
+	for _, action := range formActions {
+		tests = append(tests, struct {
+			action string
+			valid  bool
+		}{action, true})
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		form.Set("blankvalues", "40")
+		form.Set("seed", "1")
+		rec := postForm(tt.action, form)
+		invalid := strings.Contains(rec.Body.String(), "invalid action")
+		if invalid == tt.valid {
+			t.Errorf("action %q: reported invalid %v, want %v", tt.action, invalid, !tt.valid)
+		}
+		if !tt.valid && (rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), strings.Join(formActions, ", "))) {
+			t.Errorf("action %q: status code %d with %q, want 400 listing the actions", tt.action, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBadEntries(t *testing.T) {
+	defer func(s string) { *badEntries = s }(*badEntries)
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		policy   string
+		badcells string // reported at the last evaluate
+		value    string // of the cell after evaluate
+		class    string
+		reported string // badcells after evaluate
+	}{
+		{"keep", "", "x", "invalid", "0_2_0"},
+		{"keep", "0_2_0", "x", "invalid", "0_2_0"},
+		{"clear", "", "x", "invalid", "0_2_0"},
+		{"clear", "0_2_0", "", "valid", ""},
+		{"clear", "0_3_1", "x", "invalid", "0_2_0"},
+	}
+	for _, tt := range tests {
+		*badEntries = tt.policy
+		form := boardForm(puzzle, puzzle)
+		form.Set("0_2_0", "x")
+		form.Set("badcells", tt.badcells)
+		body := postForm("evaluate", form).Body.String()
+		cell := `name="0_2_0" value="` + tt.value + `" class="` + tt.class + `"`
+		if !strings.Contains(body, cell) {
+			t.Errorf("%s after %q: page does not show %s", tt.policy, tt.badcells, cell)
+		}
+		if field := `name="badcells" value="` + tt.reported + `"`; !strings.Contains(body, field) {
+			t.Errorf("%s after %q: page does not show %s", tt.policy, tt.badcells, field)
+		}
+	}
+}
+
+func TestStrictEvaluate(t *testing.T) {
+	defer func(old bool) { *strict = old }(*strict)
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		strict bool
+		entry  string
+		code   int
+		status string
+	}{
+		{true, "x", http.StatusBadRequest, "Status: Rejected, R1C3 holds &#34;x&#34;, enter only digits 1-9"},
+		{true, "0", http.StatusBadRequest, "Status: Rejected, R1C3 holds &#34;0&#34;, enter only digits 1-9"},
+		{true, "4", http.StatusOK, "Status: Valid Puzzle"},
+		{false, "x", http.StatusOK, "Status: Invalid, 0 row, 0 column, 0 box conflicts, 1 bad values"},
+	}
+	for _, tt := range tests {
+		*strict = tt.strict
+		form := boardForm(puzzle, puzzle)
+		form.Set("0_2_0", tt.entry)
+		rec := postForm("evaluate", form)
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), `value="`+tt.status+`"`) {
+			t.Errorf("strict %v entry %q: status code %d, want %d showing %q", tt.strict, tt.entry, rec.Code, tt.code, tt.status)
+		}
+	}
+}
+
+func TestDuplicateCell(t *testing.T) {
+	puzzle := mustGrid(t, testPuzzle)
+	tests := []struct {
+		name string
+		add  [][2]string // extra field values added to the form
+		want string
+	}{
+		{"well formed", nil, ""},
+		{"field twice", [][2]string{{"0_2_0", "4"}, {"0_2_0", "1"}}, "0_2_0"},
+		{"entry and given", [][2]string{{"0_0_0", "5"}}, "0_0_0"},
+		{"given twice", [][2]string{{"0_1_0_ro", "3"}}, "0_1_0"},
+	}
+	for _, tt := range tests {
+		form := boardForm(puzzle, puzzle)
+		for _, kv := range tt.add {
+			form.Add(kv[0], kv[1])
+		}
+		req := httptest.NewRequest(http.MethodPost, patternSubmit, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if err := req.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := duplicateCell(req); got != tt.want {
+			t.Errorf("%s: duplicateCell = %q, want %q", tt.name, got, tt.want)
+		}
+		if rec := postForm("evaluate", form); (rec.Code == http.StatusBadRequest) != (tt.want != "") {
+			t.Errorf("%s: evaluate status code %d", tt.name, rec.Code)
+		}
+	}
+}
+
+// collectResults runs subregionResults on the grid, one result per subregion
+func collectResults(g Grid) map[int]result {
+	out := make(chan result)
+	g.subregionResults(out)
+	res := make(map[int]result)
+	for i := 0; i < subgrids; i++ {
+		r := <-out
+		res[(r.y/3)*3+r.x/3] = r
+	}
+	return res
+}
+
+// TestWorkers finds the subregion results with each number of workers,
+// out of range counts being clamped to 1-9
+func TestWorkers(t *testing.T) {
+	defer func(n int) { *workers = n }(*workers)
+	g := mustGrid(t, testPuzzle)
+	*workers = subgrids
+	want := fmt.Sprint(collectResults(g))
+	for _, n := range []int{-1, 0, 1, 2, 4, 8, 9, 20} {
+		*workers = n
+		if got := fmt.Sprint(collectResults(g)); got != want {
+			t.Errorf("workers %d: results %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestSubregionResults(t *testing.T) {
+	tests := []struct {
+		name string
+		g    Grid
+	}{
+		{"puzzle", mustGrid(t, testPuzzle)},
+		{"stalls", mustGrid(t, testStalls)},
+		{"empty", Grid{}},
+		{"solved", mustGrid(t, testSolution)},
+	}
+	for _, tt := range tests {
+		res := collectResults(tt.g)
+		if len(res) != subgrids {
+			t.Errorf("%s: results for %d subregions, want %d", tt.name, len(res), subgrids)
+			continue
+		}
+		for sg, r := range res {
+			empty, min := 0, 10
+			for _, rc := range unit(2, sg) {
+				if tt.g[rc[0]][rc[1]] == 0 {
+					empty++
+					if n := bits.OnesCount16(tt.g.Candidates(rc[0], rc[1])); n < min {
+						min = n
+					}
+				}
+			}
+			if r.notAssigned != empty {
+				t.Errorf("%s: box %d has %d empty cells, reported %d", tt.name, sg+1, empty, r.notAssigned)
+			}
+			if empty == 0 {
+				continue
+			}
+			var mask uint16
+			for _, d := range r.choices {
+				mask |= 1 << d
+			}
+			if r.nchoices != min || len(r.choices) != min || mask != tt.g.Candidates(r.y, r.x) {
+				t.Errorf("%s: box %d chose %s with choices %v, want a cell with %d candidates", tt.name, sg+1, cellName(r.y, r.x), r.choices, min)
+			}
+		}
+	}
+}
+
+func BenchmarkSubregionResults(b *testing.B) {
+	g, _ := ParseGrid(testPuzzle)
+	out := make(chan result)
+	for i := 0; i < b.N; i++ {
+		g.subregionResults(out)
+		for j := 0; j < subgrids; j++ {
+			<-out
+		}
+	}
+}
+
+func TestCompleteBoard(t *testing.T) {
+	solution := mustGrid(t, testSolution)
+	broken := solution
+	broken[0][0], broken[0][1] = broken[0][1], broken[0][0]
+	tests := []struct {
+		action string
+		board  Grid
+		status string // shown on the page, none for a fresh puzzle
+	}{
+		{"solve", solution, "Status: Solved Puzzle, the board is already complete"},
+		{"solve", broken, "Status: Invalid, the board is complete but breaks the rules"},
+		{"new", solution, ""},
+		{"new", broken, ""},
+	}
+	for _, tt := range tests {
+		form := boardForm(Grid{}, tt.board)
+		form.Set("blankvalues", "40")
+		form.Set("seed", "1")
+		rec := postForm(tt.action, form)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s: status code %d, want 200", tt.action, tt.board, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if tt.status != "" {
+			if !strings.Contains(body, tt.status) {
+				t.Errorf("%s %s: page does not show %q", tt.action, tt.board, tt.status)
+			}
+			continue
+		}
+		if strings.Contains(body, "already complete") || strings.Contains(body, "is complete but") {
+			t.Errorf("%s %s: checked the board instead of generating a puzzle", tt.action, tt.board)
+		}
+		if n := strings.Count(body, `_ro"`); n != rows*cols-40 {
+			t.Errorf("%s %s: new puzzle has %d givens, want %d", tt.action, tt.board, n, rows*cols-40)
+		}
+	}
+}
+
+func TestHandleRoot(t *testing.T) {
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+		body        string // the response holds it, or is the redirect to it
+	}{
+		{"/", http.StatusFound, "", pattern},
+		{"/puzzle", http.StatusNotFound, "text/html; charset=utf-8", `There is no page at /puzzle.`},
+		{"/<b>", http.StatusNotFound, "text/html; charset=utf-8", `There is no page at /&lt;b&gt;.`},
+		{"/api/nothing", http.StatusNotFound, "application/json", `"error":"no API endpoint at /api/nothing"`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handleRoot(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: status code %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if rec.Code == http.StatusFound {
+			if loc := rec.Header().Get("Location"); loc != tt.body {
+				t.Errorf("%s: redirects to %q, want %q", tt.path, loc, tt.body)
+			}
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+			t.Errorf("%s: Content-Type %q, want %q", tt.path, ct, tt.contentType)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, tt.body) {
+			t.Errorf("%s: response %q does not hold %q", tt.path, body, tt.body)
+		}
+	}
+
+	// the 404 page points to the puzzle
+	rec := httptest.NewRecorder()
+	handleRoot(rec, httptest.NewRequest(http.MethodGet, "/puzzle", nil))
+	if !strings.Contains(rec.Body.String(), `<a href="`+pattern+`">`) {
+		t.Errorf("404 page does not link to %s", pattern)
+	}
+}